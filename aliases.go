@@ -0,0 +1,149 @@
+package solarwind
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// RedirectOptions controls what is written for the `aliases` of pages.
+type RedirectOptions struct {
+	// Stubs writes an HTML page at every alias that sends browsers on to
+	// the page.
+	Stubs bool `json:"stubs"`
+
+	// Netlify writes the aliases to _redirects in the output directory.
+	Netlify bool `json:"netlify"`
+
+	// Nginx writes the aliases to redirects.map in the output directory,
+	// for use in an nginx map block.
+	Nginx bool `json:"nginx"`
+}
+
+var DefaultRedirectOptions = RedirectOptions{Stubs: true}
+
+const (
+	NetlifyRedirectsFile = "_redirects"
+	NginxRedirectsFile   = "redirects.map"
+)
+
+// Alias is an old URL of a page.
+type Alias struct {
+	From   string
+	To     string
+	Source string
+}
+
+// CollectAliases gathers the aliases of pages, sorted by URL. Two pages
+// claiming the same alias is an error.
+func CollectAliases(pages []*MarkdownPage) ([]Alias, error) {
+	seen := map[string]Alias{}
+	for _, page := range pages {
+		for _, from := range page.Aliases {
+			alias := Alias{From: normalizeAlias(from), To: "/" + page.RelLink, Source: page.sourceFile}
+			if other, ok := seen[alias.From]; ok && other.To != alias.To {
+				return nil, fmt.Errorf("%s and %s both have the alias %s", other.Source, alias.Source, alias.From)
+			}
+			seen[alias.From] = alias
+		}
+	}
+
+	aliases := make([]Alias, 0, len(seen))
+	for _, alias := range seen {
+		aliases = append(aliases, alias)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].From < aliases[j].From
+	})
+	return aliases, nil
+}
+
+func normalizeAlias(from string) string {
+	cleaned := path.Clean("/" + strings.TrimSpace(from))
+	if strings.HasSuffix(from, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// AliasFile returns the file in dir that the stub for alias is written to.
+// Aliases without an extension are directories.
+func AliasFile(dir, alias string) string {
+	if strings.HasSuffix(alias, "/") || path.Ext(alias) == "" {
+		return path.Join(dir, alias, "index.html")
+	}
+	return path.Join(dir, alias)
+}
+
+// WriteAliases writes the redirects for aliases into dir.
+func WriteAliases(dir string, aliases []Alias, options RedirectOptions, baseURL string) error {
+	netlify := &bytes.Buffer{}
+	nginx := &bytes.Buffer{}
+	for _, alias := range aliases {
+		if options.Stubs {
+			to := alias.To
+			if baseURL != "" {
+				to = strings.TrimSuffix(baseURL, "/") + to
+			}
+			if err := WriteRedirect(AliasFile(dir, alias.From), to); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(netlify, "%s %s 301\n", alias.From, alias.To)
+		fmt.Fprintf(nginx, "%s %s;\n", alias.From, alias.To)
+	}
+
+	if options.Netlify {
+		if err := ioutil.WriteFile(path.Join(dir, NetlifyRedirectsFile), netlify.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	if options.Nginx {
+		if err := ioutil.WriteFile(path.Join(dir, NginxRedirectsFile), nginx.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setAliases remembers the aliases of the last build for AliasHandler.
+func (s *Site) setAliases(aliases []Alias) {
+	redirects := map[string]string{}
+	for _, alias := range aliases {
+		redirects[aliasKey(alias.From)] = alias.To
+	}
+	s.aliases.Lock()
+	s.aliases.redirects = redirects
+	s.aliases.Unlock()
+}
+
+// AliasHandler redirects requests for the aliases of the last build to
+// their pages and hands everything else to next. An alias without an
+// extension is a directory, so /old, /old/ and /old/index.html all match it.
+func (s *Site) AliasHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.aliases.RLock()
+		to, ok := s.aliases.redirects[aliasKey(r.URL.Path)]
+		s.aliases.RUnlock()
+
+		if ok {
+			http.Redirect(w, r, to, http.StatusMovedPermanently)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// aliasKey is what AliasHandler looks a request path up by, without any
+// trailing slash or index.html.
+func aliasKey(p string) string {
+	p = strings.TrimSuffix(p, "index.html")
+	if p != "/" {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return p
+}
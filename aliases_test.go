@@ -0,0 +1,110 @@
+package solarwind
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCollectAliases(t *testing.T) {
+	pages := []*MarkdownPage{
+		{RelLink: "posts/new.html", Aliases: []string{"posts/old.html", "/older/"}, sourceFile: "a.md"},
+		{RelLink: "about.html", Aliases: []string{"/about-us"}, sourceFile: "about.md"},
+	}
+	aliases, err := CollectAliases(pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Alias{
+		{"/about-us", "/about.html", "about.md"},
+		{"/older/", "/posts/new.html", "a.md"},
+		{"/posts/old.html", "/posts/new.html", "a.md"},
+	}
+	if len(aliases) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, aliases)
+	}
+	for i := range expected {
+		if aliases[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], aliases[i])
+		}
+	}
+
+	pages = append(pages, &MarkdownPage{RelLink: "posts/other.html", Aliases: []string{"/posts/old.html"}, sourceFile: "b.md"})
+	if _, err := CollectAliases(pages); err == nil {
+		t.Error("expected two pages with the same alias to be an error")
+	}
+}
+
+func TestWriteAliases(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	aliases := []Alias{
+		{From: "/older/", To: "/posts/new.html"},
+		{From: "/posts/old.html", To: "/posts/new.html"},
+	}
+	options := RedirectOptions{Stubs: true, Netlify: true, Nginx: true}
+	if err := WriteAliases(root, aliases, options, "https://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stub := range []string{"older/index.html", "posts/old.html"} {
+		content, err := ioutil.ReadFile(filepath.Join(root, stub))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), `<link rel="canonical" href="https://example.com/posts/new.html">`) ||
+			!strings.Contains(string(content), `content="0; url=https://example.com/posts/new.html"`) {
+			t.Errorf("unexpected stub %s:\n%s", stub, content)
+		}
+	}
+
+	netlify, err := ioutil.ReadFile(filepath.Join(root, NetlifyRedirectsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(netlify) != "/older/ /posts/new.html 301\n/posts/old.html /posts/new.html 301\n" {
+		t.Errorf("unexpected _redirects:\n%s", netlify)
+	}
+	nginx, err := ioutil.ReadFile(filepath.Join(root, NginxRedirectsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(nginx) != "/older/ /posts/new.html;\n/posts/old.html /posts/new.html;\n" {
+		t.Errorf("unexpected redirects.map:\n%s", nginx)
+	}
+}
+
+func TestAliasHandler(t *testing.T) {
+	site := New(&Config{})
+	site.setAliases([]Alias{
+		{From: "/older/", To: "/posts/new.html"},
+		{From: "/oldest", To: "/posts/new.html"},
+	})
+
+	handler := site.AliasHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("file"))
+	}))
+
+	for _, p := range []string{"/older/", "/older/index.html", "/older", "/oldest", "/oldest/", "/oldest/index.html"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", p, nil))
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/posts/new.html" {
+			t.Errorf("%s: expected a redirect, got %d %s", p, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/posts/new.html", nil))
+	if w.Body.String() != "file" {
+		t.Errorf("expected the file to be served, got %d", w.Code)
+	}
+}
@@ -0,0 +1,202 @@
+package solarwind
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/tdewolff/minify"
+	"github.com/tdewolff/minify/css"
+	"github.com/tdewolff/minify/js"
+)
+
+// fingerprintLength is how many hex characters of the content hash end up in
+// a fingerprinted filename.
+const fingerprintLength = 8
+
+// AssetOptions controls what the asset pipeline does to bundles.
+type AssetOptions struct {
+	Minify      bool `json:"minify"`
+	Fingerprint bool `json:"fingerprint"`
+}
+
+// Asset is a bundle written by the asset pipeline.
+type Asset struct {
+	// Name is the path of the written file relative to the static directory.
+	Name string
+
+	// URL is the root relative URL of the written file.
+	URL string
+
+	// Integrity is a subresource integrity value for the file's content.
+	Integrity string
+}
+
+// AssetPipeline concatenates, minifies and fingerprints static files on
+// behalf of templates. Each bundle is only built once per pipeline, no matter
+// how many pages ask for it.
+type AssetPipeline struct {
+	Options AssetOptions
+
+	// SourceDirs are searched in order for the files going into a bundle.
+	SourceDirs []string
+
+	// OutputDir is where bundles are written. They are served from /static/.
+	OutputDir string
+
+	minifier *minify.M
+	assets   map[string]*Asset
+	sources  map[string]string
+}
+
+func NewAssetPipeline(options AssetOptions, sourceDirs []string, outputDir string) *AssetPipeline {
+	m := minify.New()
+	m.AddFunc("text/css", css.Minify)
+	m.AddFunc("application/javascript", js.Minify)
+
+	return &AssetPipeline{
+		Options:    options,
+		SourceDirs: sourceDirs,
+		OutputDir:  outputDir,
+		minifier:   m,
+		assets:     map[string]*Asset{},
+		sources:    map[string]string{},
+	}
+}
+
+// TemplateFuncs returns the template functions backed by the pipeline:
+//
+//	{{ with bundle "app.css" "css/reset.css" "css/site.css" }}
+//	  <link rel="stylesheet" href="{{ .URL }}" integrity="{{ .Integrity }}">
+//	{{ end }}
+//	{{ with fingerprint "js/site.js" }}<script src="{{ .URL }}"></script>{{ end }}
+func (p *AssetPipeline) TemplateFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"bundle":      p.Bundle,
+		"fingerprint": p.Fingerprint,
+	}
+}
+
+// Fingerprint runs a single static file through the pipeline.
+func (p *AssetPipeline) Fingerprint(file string) (*Asset, error) {
+	return p.Bundle(file, file)
+}
+
+// Bundle concatenates files, which are relative to the static directories,
+// into a single asset called name.
+func (p *AssetPipeline) Bundle(name string, files ...string) (*Asset, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("bundle %s has no files", name)
+	}
+
+	key := strings.Join(files, "\x00")
+	if asset, ok := p.assets[name]; ok {
+		if p.sources[name] != key {
+			return nil, fmt.Errorf("bundle %s is used with different files on different pages", name)
+		}
+		return asset, nil
+	}
+
+	content, err := p.concatenate(name, files)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Options.Minify {
+		if mediatype := assetMediaType(name); mediatype != "" {
+			minified, err := p.minifier.Bytes(mediatype, content)
+			if err != nil {
+				return nil, fmt.Errorf("could not minify bundle %s: %s", name, err)
+			}
+			content = minified
+		}
+	}
+
+	written := name
+	if p.Options.Fingerprint {
+		written = FingerprintName(name, content)
+	}
+
+	dest := path.Join(p.OutputDir, written)
+	if err := os.MkdirAll(path.Dir(dest), 0755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(dest, content, 0644); err != nil {
+		return nil, err
+	}
+
+	asset := &Asset{
+		Name:      written,
+		URL:       "/static/" + written,
+		Integrity: Integrity(content),
+	}
+	p.assets[name] = asset
+	p.sources[name] = key
+	return asset, nil
+}
+
+func (p *AssetPipeline) concatenate(name string, files []string) ([]byte, error) {
+	separator := []byte("\n")
+	if assetMediaType(name) == "application/javascript" {
+		// Guard against files that don't end their last statement.
+		separator = []byte(";\n")
+	}
+
+	b := &bytes.Buffer{}
+	for i, file := range files {
+		content, err := p.read(file)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.Write(separator)
+		}
+		b.Write(content)
+	}
+	return b.Bytes(), nil
+}
+
+func (p *AssetPipeline) read(file string) ([]byte, error) {
+	for _, dir := range p.SourceDirs {
+		content, err := ioutil.ReadFile(path.Join(dir, file))
+		if err == nil {
+			return content, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("asset %s was not found in %s", file, strings.Join(p.SourceDirs, " or "))
+}
+
+func assetMediaType(name string) string {
+	switch path.Ext(name) {
+	case ".css":
+		return "text/css"
+	case ".js":
+		return "application/javascript"
+	}
+	return ""
+}
+
+// FingerprintName puts a hash of content into name, so css/app.css becomes
+// css/app.3f9a1c2b.css.
+func FingerprintName(name string, content []byte) string {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])[:fingerprintLength]
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// Integrity returns a subresource integrity value for content.
+func Integrity(content []byte) string {
+	sum := sha512.Sum384(content)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,106 @@
+package solarwind
+
+import (
+	"context"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestBundleMinifiesAndFingerprints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	project := filepath.Join(dir, "static")
+	theme := filepath.Join(dir, "theme")
+	out := filepath.Join(dir, "public", "static")
+	for p, content := range map[string]string{
+		filepath.Join(project, "css", "site.css"): "body {\n  color: red;\n}\n",
+		filepath.Join(theme, "css", "reset.css"):  "html {\n  margin: 0px;\n}\n",
+		filepath.Join(theme, "css", "site.css"):   "body { color: blue; }",
+	} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pipeline := NewAssetPipeline(AssetOptions{Minify: true, Fingerprint: true}, []string{project, theme}, out)
+	asset, err := pipeline.Bundle("css/app.css", "css/reset.css", "css/site.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !regexp.MustCompile(`^/static/css/app\.[0-9a-f]{8}\.css$`).MatchString(asset.URL) {
+		t.Errorf("expected a fingerprinted URL, got %s", asset.URL)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(out, filepath.FromSlash(asset.Name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "html{margin:0}body{color:red}" {
+		t.Errorf("unexpected bundle content %q", content)
+	}
+	if asset.Integrity != Integrity(content) {
+		t.Errorf("unexpected integrity %s", asset.Integrity)
+	}
+
+	again, err := pipeline.Bundle("css/app.css", "css/reset.css", "css/site.css")
+	if err != nil || again != asset {
+		t.Errorf("expected the bundle to be built once, got %v, %v", again, err)
+	}
+	if _, err := pipeline.Bundle("css/app.css", "css/site.css"); err == nil {
+		t.Error("expected reusing a bundle name with other files to fail")
+	}
+}
+
+func TestBuiltAssetsMatchTheirIntegrity(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTestFiles(t, root, map[string]string{
+		Solarwindfile:          `{"assets": {"minify": true, "fingerprint": false}}`,
+		"static/js/site.js":    "function hello() {\n  return 1;\n}\n",
+		"templates/index.html": `{{ with fingerprint "js/site.js" }}<script src="{{ .URL }}" integrity="{{ .Integrity }}"></script>{{ end }}{{ template "body" . }}`,
+		"templates/page.html":  `{{ define "body" }}{{ template "content" . }}{{ end }}`,
+		"templates/post.html":  `{{ define "body" }}{{ .CurrentPage.FinalHTML }}{{ end }}`,
+		"content/index.md":     "Home",
+		"content/posts/a.md":   "###\ntitle: A\n###\n\nA post",
+	})
+
+	site, err := Open(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := site.Build(context.Background(), site.DestinationDir); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := ioutil.ReadFile(filepath.Join(site.DestinationDir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := regexp.MustCompile(`integrity="([^"]+)"`).FindSubmatch(page)
+	if match == nil {
+		t.Fatalf("expected an integrity attribute in %s", page)
+	}
+	script, err := ioutil.ReadFile(filepath.Join(site.DestinationDir, "static", "js", "site.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Integrity(script) != html.UnescapeString(string(match[1])) {
+		t.Errorf("expected the integrity of the page to match %q", script)
+	}
+}
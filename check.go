@@ -0,0 +1,359 @@
+package solarwind
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// htmlIgnored matches the parts of a page whose contents aren't markup.
+	htmlIgnored = regexp.MustCompile(`(?is)<!--.*?-->|<script\b[^>]*>(.*?)</script>|<style\b[^>]*>(.*?)</style>`)
+	htmlTagOpen = regexp.MustCompile(`(?i)<[a-z][a-z0-9]*\b[^>]*>`)
+	htmlAttr    = regexp.MustCompile(`(?i)\s([a-z][a-z0-9-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// linkAttributes are the attributes of a tag that link to something.
+var linkAttributes = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"srcset": true,
+	"poster": true,
+}
+
+// BrokenLink is a link in a generated page that goes nowhere.
+type BrokenLink struct {
+	File   string
+	Line   int
+	URL    string
+	Reason string
+}
+
+func (b BrokenLink) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", b.File, b.Line, b.URL, b.Reason)
+}
+
+// LinkReport is what a LinkChecker found.
+type LinkReport struct {
+	Broken []BrokenLink
+
+	// Orphans are pages no other page links to. Index pages, 404.html and
+	// redirects, like the stubs written for aliases, are never orphans.
+	Orphans []string
+}
+
+// LinkChecker checks the links between the pages of a generated site.
+type LinkChecker struct {
+	// Dir is the output directory.
+	Dir string
+
+	// BaseURL is the site's base_url. Absolute links to it are checked like
+	// internal links.
+	BaseURL string
+
+	// External also checks links to other sites with HTTP requests.
+	External bool
+	Client   *http.Client
+}
+
+func NewLinkChecker(dir, baseURL string, external bool) *LinkChecker {
+	return &LinkChecker{
+		Dir:      dir,
+		BaseURL:  baseURL,
+		External: external,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// htmlLink is a link found in a page.
+type htmlLink struct {
+	url  string
+	line int
+}
+
+// htmlDocument is what the checker needs to know about a page.
+type htmlDocument struct {
+	links []htmlLink
+	ids   map[string]bool
+
+	// redirect is set for pages with a meta refresh.
+	redirect bool
+}
+
+// Check reads every HTML file in the output directory and reports the links
+// that don't resolve, the anchors that don't exist and the orphaned pages.
+func (c *LinkChecker) Check() (*LinkReport, error) {
+	docs := map[string]*htmlDocument{}
+	err := filepath.Walk(c.Dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !isHTMLFile(p) {
+			return nil
+		}
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(c.Dir, p)
+		if err != nil {
+			return err
+		}
+		docs[filepath.ToSlash(rel)] = parseHTMLDocument(string(content))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(docs))
+	for file := range docs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	report := &LinkReport{}
+	linked := map[string]bool{}
+	external := map[string]string{}
+	for _, file := range files {
+		for _, link := range docs[file].links {
+			broken := BrokenLink{File: path.Join(c.Dir, file), Line: link.line, URL: link.url}
+
+			target, fragment, internal, ok := c.resolve(file, link.url)
+			if !ok {
+				continue
+			}
+			if !internal {
+				if c.External {
+					reason, checked := external[target]
+					if !checked {
+						reason = c.checkExternal(target)
+						external[target] = reason
+					}
+					if reason != "" {
+						broken.Reason = reason
+						report.Broken = append(report.Broken, broken)
+					}
+				}
+				continue
+			}
+
+			target, exists := c.find(target)
+			if !exists {
+				broken.Reason = "no such file"
+				report.Broken = append(report.Broken, broken)
+				continue
+			}
+			if target != file {
+				linked[target] = true
+			}
+			if fragment == "" {
+				continue
+			}
+			if doc, ok := docs[target]; ok && !doc.ids[fragment] {
+				broken.Reason = fmt.Sprintf("no element with id %q", fragment)
+				report.Broken = append(report.Broken, broken)
+			}
+		}
+	}
+
+	for _, file := range files {
+		base := path.Base(file)
+		if !linked[file] && !docs[file].redirect && base != "index.html" && base != "404.html" {
+			report.Orphans = append(report.Orphans, path.Join(c.Dir, file))
+		}
+	}
+	return report, nil
+}
+
+// resolve works out what link in file points at. Internal targets are paths
+// relative to the output directory. ok is false for links that can't be
+// checked, like mailto: links.
+func (c *LinkChecker) resolve(file, link string) (target, fragment string, internal, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return link, "", false, false
+	}
+
+	base, _ := url.Parse(c.BaseURL)
+	if base == nil {
+		base = &url.URL{}
+	}
+	basePath := strings.TrimSuffix(base.Path, "/") + "/"
+
+	if u.Scheme != "" || u.Host != "" {
+		if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "" {
+			return link, "", false, false
+		}
+		sameSite := base.Host != "" && strings.EqualFold(u.Host, base.Host) && (u.Scheme == "" || u.Scheme == base.Scheme)
+		if sameSite {
+			if p, ok := sitePath(u.Path, basePath); ok {
+				return p, u.Fragment, true, true
+			}
+		}
+		if u.Scheme == "" {
+			u.Scheme = "http"
+		}
+		u.Fragment = ""
+		return u.String(), "", false, true
+	}
+
+	if u.Path == "" {
+		// A link to an anchor on the same page.
+		return file, u.Fragment, true, true
+	}
+	if strings.HasPrefix(u.Path, "/") {
+		p, ok := sitePath(u.Path, basePath)
+		if !ok {
+			// Somewhere else on the host the site is served from.
+			if base.Host == "" {
+				return link, "", false, false
+			}
+			abs := *base
+			abs.Path, abs.RawQuery, abs.Fragment = u.Path, u.RawQuery, ""
+			return abs.String(), "", false, true
+		}
+		return p, u.Fragment, true, true
+	}
+	p := path.Join(path.Dir(file), u.Path)
+	if strings.HasSuffix(u.Path, "/") {
+		p += "/"
+	}
+	return p, u.Fragment, true, true
+}
+
+// sitePath returns the path in the output directory of the root relative
+// URL path p, for a site served from basePath. ok is false if p isn't under
+// basePath.
+func sitePath(p, basePath string) (string, bool) {
+	if p+"/" == basePath {
+		return ".", true
+	}
+	if !strings.HasPrefix(p, basePath) {
+		return "", false
+	}
+	rel := strings.TrimPrefix(p, basePath)
+	if rel == "" {
+		return ".", true
+	}
+	cleaned := path.Clean(rel)
+	if strings.HasSuffix(rel, "/") {
+		cleaned += "/"
+	}
+	return cleaned, true
+}
+
+// find returns the file target refers to, which is index.html for
+// directories.
+func (c *LinkChecker) find(target string) (string, bool) {
+	target = path.Clean(target)
+	info, err := os.Stat(path.Join(c.Dir, target))
+	if err != nil {
+		return target, false
+	}
+	if info.IsDir() {
+		target = path.Join(target, "index.html")
+		if _, err := os.Stat(path.Join(c.Dir, target)); err != nil {
+			return target, false
+		}
+	}
+	return target, true
+}
+
+// checkExternal returns why link is broken, or an empty string if it isn't.
+func (c *LinkChecker) checkExternal(link string) string {
+	resp, err := c.Client.Head(link)
+	if err == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		resp.Body.Close()
+		resp, err = c.Client.Get(link)
+	}
+	if err != nil {
+		return err.Error()
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return resp.Status
+	}
+	return ""
+}
+
+func isHTMLFile(p string) bool {
+	ext := strings.ToLower(path.Ext(p))
+	return ext == ".html" || ext == ".htm"
+}
+
+// parseHTMLDocument finds the links and element ids in a page. It isn't a
+// real HTML parser, but the output of the generator is regular enough that
+// looking at tags and their attributes works.
+func parseHTMLDocument(content string) *htmlDocument {
+	// Blank out comments and the insides of scripts and styles without
+	// moving anything, so offsets still give the right line numbers.
+	b := []byte(content)
+	for _, loc := range htmlIgnored.FindAllStringSubmatchIndex(content, -1) {
+		start, end := loc[0], loc[1]
+		for group := 1; group < len(loc)/2; group++ {
+			if loc[2*group] >= 0 {
+				start, end = loc[2*group], loc[2*group+1]
+			}
+		}
+		for i := start; i < end; i++ {
+			if b[i] != '\n' {
+				b[i] = ' '
+			}
+		}
+	}
+	masked := string(b)
+
+	doc := &htmlDocument{ids: map[string]bool{}}
+	lines := newlineOffsets(content)
+	for _, tag := range htmlTagOpen.FindAllStringIndex(masked, -1) {
+		line := sort.SearchInts(lines, tag[0]) + 1
+		lower := strings.ToLower(masked[tag[0]:tag[1]])
+		isAnchor := strings.HasPrefix(lower, "<a ")
+		isMeta := strings.HasPrefix(lower, "<meta ")
+		for _, attr := range htmlAttr.FindAllStringSubmatch(masked[tag[0]:tag[1]], -1) {
+			name := strings.ToLower(attr[1])
+			value := html.UnescapeString(attr[2] + attr[3] + attr[4])
+			switch {
+			case name == "id" || (name == "name" && isAnchor):
+				doc.ids[value] = true
+			case name == "http-equiv" && isMeta && strings.EqualFold(value, "refresh"):
+				doc.redirect = true
+			case name == "srcset":
+				for _, candidate := range strings.Split(value, ",") {
+					if fields := strings.Fields(candidate); len(fields) > 0 {
+						doc.links = append(doc.links, htmlLink{fields[0], line})
+					}
+				}
+			case linkAttributes[name]:
+				doc.links = append(doc.links, htmlLink{value, line})
+			}
+		}
+	}
+	return doc
+}
+
+// newlineOffsets returns the offset of every newline in content.
+func newlineOffsets(content string) []int {
+	var offsets []int
+	for i, c := range content {
+		if c == '\n' {
+			offsets = append(offsets, i)
+		}
+	}
+	return offsets
+}
+
+// CheckLinks runs a LinkChecker over the output directory of the site.
+func (s *Site) CheckLinks(external bool) (*LinkReport, error) {
+	return NewLinkChecker(s.DestinationDir, s.Config.BaseURL, external).Check()
+}
@@ -0,0 +1,119 @@
+package solarwind
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkChecker(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	writeTestFiles(t, root, map[string]string{
+		"index.html": `<a href="/posts/a.html">A</a>
+<a href="posts/a.html#intro">A intro</a>
+<a href="https://example.com/posts/b.html">B</a>
+<img src="/static/missing.png">
+<!-- <a href="/commented.html"> -->
+<script>var a = '<a href="/scripted.html">';</script>
+<a href="mailto:me@example.com">Mail</a>
+<a href="` + server.URL + `/ok">OK</a>
+<a href="` + server.URL + `/gone">Gone</a>`,
+		"posts/a.html": `<h1 id="intro">A</h1>
+<a href="#outro">Outro</a>
+<a href="../">Home</a>`,
+		"posts/b.html":      `<a name="top"></a><a href="/posts/b.html#top">Top</a>`,
+		"posts/orphan.html": `<p>Nobody links here</p>`,
+		"posts/old.html":    fmt.Sprintf(redirectTemplate, "https://example.com/posts/a.html", "https://example.com/posts/a.html", "https://example.com/posts/a.html"),
+	})
+
+	checker := NewLinkChecker(root, "https://example.com/", false)
+	report, err := checker.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []BrokenLink{
+		{filepath.Join(root, "index.html"), 4, "/static/missing.png", "no such file"},
+		{filepath.Join(root, "posts/a.html"), 2, "#outro", `no element with id "outro"`},
+	}
+	if len(report.Broken) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, report.Broken)
+	}
+	for i := range expected {
+		if report.Broken[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], report.Broken[i])
+		}
+	}
+	if len(report.Orphans) != 1 || report.Orphans[0] != filepath.Join(root, "posts/orphan.html") {
+		t.Errorf("unexpected orphans %v", report.Orphans)
+	}
+
+	checker.External = true
+	report, err = checker.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Broken) != 3 || report.Broken[1].URL != server.URL+"/gone" || report.Broken[1].Line != 9 {
+		t.Errorf("expected the gone link to be broken, got %v", report.Broken)
+	}
+}
+
+func TestLinkCheckerBaseURL(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTestFiles(t, root, map[string]string{
+		"index.html": `<a href="/blog/posts/a.html">A</a>
+<a href="/blog/">Home</a>
+<a href="https://example.com/blog/posts/a.html#intro">A intro</a>
+<a href="//example.com/blog">Home</a>
+<a href="/blog/posts/missing.html">Missing</a>
+<a href="https://example.com/blog/posts/gone.html">Gone</a>
+<a href="/elsewhere.html">Another app</a>
+<a href="https://example.com.evil.org/blog/posts/a.html">Lookalike</a>`,
+		"posts/a.html": `<h1 id="intro">A</h1>`,
+	})
+
+	for _, baseURL := range []string{"https://example.com/blog/", "https://example.com/blog"} {
+		report, err := NewLinkChecker(root, baseURL, false).Check()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Broken) != 2 || report.Broken[0].Line != 5 || report.Broken[1].Line != 6 {
+			t.Errorf("%s: expected the missing and gone links to be broken, got %v", baseURL, report.Broken)
+		}
+		if len(report.Orphans) != 0 {
+			t.Errorf("%s: expected posts/a.html to be linked, got %v", baseURL, report.Orphans)
+		}
+	}
+
+	writeTestFiles(t, root, map[string]string{
+		"index.html": `<a href="https://example.com.evil.org/posts/a.html">Lookalike</a>`,
+	})
+	report, err := NewLinkChecker(root, "https://example.com", false).Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Orphans) != 1 {
+		t.Errorf("expected a link to another host not to count, got %v", report.Orphans)
+	}
+}
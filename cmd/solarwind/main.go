@@ -0,0 +1,13 @@
+// Command solarwind builds, serves and deploys solarwind projects. It's the
+// command package without any plugins.
+package main
+
+import (
+	"os"
+
+	"github.com/kyleterry/solarwind/command"
+)
+
+func main() {
+	os.Exit(command.Main())
+}
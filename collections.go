@@ -0,0 +1,144 @@
+package solarwind
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// CollectionOptions turns a data file into pages. With
+//
+//	"collections": {"products": {"template": "product.html", "slug_field": "name"}}
+//
+// every entry in data/products.yaml is rendered through
+// templates/product.html to public/products/<slug>.html.
+type CollectionOptions struct {
+	// Data is the data file the entries come from, such as "products" or
+	// "talks/2015". Defaults to the collection name.
+	Data string `json:"data"`
+
+	// Template renders each entry the way post.html renders posts.
+	// Defaults to <collection name>.html.
+	Template string `json:"template"`
+
+	// Path is the directory under the output directory the pages are
+	// written to. Defaults to the collection name. It can't lead out of
+	// the output directory.
+	Path string `json:"path"`
+
+	// SlugField is the entry field the page slug is made from. Defaults to
+	// the title field. Entries of a map that don't have it use their key.
+	SlugField string `json:"slug_field"`
+
+	// TitleField is the entry field used as the page title. Defaults to
+	// "title".
+	TitleField string `json:"title_field"`
+}
+
+// withDefaults fills in the options that default to the collection name and
+// cleans Path.
+func (o CollectionOptions) withDefaults(name string) CollectionOptions {
+	if o.Data == "" {
+		o.Data = name
+	}
+	if o.Template == "" {
+		o.Template = name + ".html"
+	}
+	if o.Path == "" {
+		o.Path = name
+	}
+	// Paths are relative to the output directory whether or not they
+	// start with a slash.
+	o.Path = path.Clean(strings.TrimPrefix(o.Path, "/"))
+	if o.TitleField == "" {
+		o.TitleField = "title"
+	}
+	if o.SlugField == "" {
+		o.SlugField = o.TitleField
+	}
+	return o
+}
+
+// BuildCollectionPages makes a page for every entry of a data collection. The
+// data can be a list of objects or an object of objects. Each page gets the
+// entry's fields as its Params, and is laid out for the root of the output
+// directory.
+func BuildCollectionPages(name string, options CollectionOptions, data map[string]interface{}) ([]MarkdownPage, error) {
+	options = options.withDefaults(name)
+
+	var value interface{} = data
+	for _, key := range strings.Split(options.Data, "/") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			value = nil
+			break
+		}
+		value = m[key]
+	}
+	if value == nil {
+		return nil, fmt.Errorf("collection %s: there is no data file called %s", name, options.Data)
+	}
+
+	type entry struct {
+		key    string
+		fields map[string]interface{}
+	}
+	var entries []entry
+	switch v := value.(type) {
+	case []interface{}:
+		for i, item := range v {
+			fields, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("collection %s: entry %d is not an object", name, i)
+			}
+			entries = append(entries, entry{fields: fields})
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fields, ok := v[key].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("collection %s: entry %s is not an object", name, key)
+			}
+			entries = append(entries, entry{key: key, fields: fields})
+		}
+	default:
+		return nil, fmt.Errorf("collection %s: %s must be a list or an object", name, options.Data)
+	}
+
+	pages := make([]MarkdownPage, 0, len(entries))
+	seen := map[string]int{}
+	for i, e := range entries {
+		source := e.key
+		if field, ok := e.fields[options.SlugField]; ok {
+			source = fmt.Sprint(field)
+		}
+		slug := PageSlug(source)
+		if slug == "" {
+			return nil, fmt.Errorf("collection %s: entry %d has no %s to make a slug from", name, i, options.SlugField)
+		}
+		if other, ok := seen[slug]; ok {
+			return nil, fmt.Errorf("collection %s: entries %d and %d both have the slug %s", name, other, i, slug)
+		}
+		seen[slug] = i
+
+		page := MarkdownPage{
+			Slug:            slug,
+			Filename:        slug,
+			DestinationFile: path.Join(options.Path, slug+".html"),
+			RelLink:         path.Join(options.Path, slug+".html"),
+			Params:          e.fields,
+		}
+		if title, ok := e.fields[options.TitleField]; ok {
+			page.Title = fmt.Sprint(title)
+		}
+		pages = append(pages, page)
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,104 @@
+package solarwind
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildCollectionPages(t *testing.T) {
+	data := map[string]interface{}{
+		"products": []interface{}{
+			map[string]interface{}{"name": "Solar Panel", "price": 100},
+			map[string]interface{}{"name": "Wind Turbine", "price": 200},
+		},
+		"talks": map[string]interface{}{
+			"2015": map[string]interface{}{
+				"gophercon": map[string]interface{}{"title": "Static Sites"},
+			},
+		},
+	}
+
+	products, err := BuildCollectionPages("products", CollectionOptions{SlugField: "name", TitleField: "name"}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(products))
+	}
+	page := products[1]
+	if page.Title != "Wind Turbine" || page.RelLink != "products/wind-turbine.html" ||
+		page.DestinationFile != "products/wind-turbine.html" || page.Params["price"] != 200 {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	talks, err := BuildCollectionPages("talks", CollectionOptions{Data: "talks/2015", SlugField: "id"}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(talks) != 1 || talks[0].Slug != "gophercon" || talks[0].Title != "Static Sites" {
+		t.Errorf("expected map entries to fall back to their key for a slug, got %+v", talks)
+	}
+}
+
+func TestSortCollectionPages(t *testing.T) {
+	data := map[string]interface{}{
+		"docs": []interface{}{
+			map[string]interface{}{"title": "A", "weight": 2, "date": "2015-03-01"},
+			map[string]interface{}{"title": "B", "weight": 1, "date": "2015-03-03"},
+			map[string]interface{}{"title": "C", "weight": 3, "date": "2015-03-02"},
+		},
+	}
+
+	tests := []struct {
+		options  SectionOptions
+		expected string
+	}{
+		{SectionOptions{SortBy: "weight", Order: SortDescending}, "CAB"},
+		{SectionOptions{SortBy: "weight", Order: SortAscending}, "BAC"},
+		{SectionOptions{SortBy: "date", Order: SortDescending}, "BCA"},
+	}
+	for _, test := range tests {
+		pages, err := BuildCollectionPages("docs", CollectionOptions{}, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		SortPages(pages, test.options)
+		var got string
+		for _, page := range pages {
+			got += page.Title
+		}
+		if got != test.expected {
+			t.Errorf("%+v: expected %s, got %s", test.options, test.expected, got)
+		}
+	}
+}
+
+func TestBuildCollectionPagesCleansPath(t *testing.T) {
+	data := map[string]interface{}{
+		"products": []interface{}{map[string]interface{}{"title": "Solar Panel"}},
+	}
+
+	for _, p := range []string{"shop/", "/shop", "./shop//"} {
+		pages, err := BuildCollectionPages("products", CollectionOptions{Path: p}, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pages[0].RelLink != "shop/solar-panel.html" || pages[0].DestinationFile != pages[0].RelLink {
+			t.Errorf("%s: unexpected page %s, %s", p, pages[0].RelLink, pages[0].DestinationFile)
+		}
+	}
+}
+
+func TestBuildCollectionPagesRejectsDuplicateSlugs(t *testing.T) {
+	data := map[string]interface{}{
+		"products": []interface{}{
+			map[string]interface{}{"title": "Update"},
+			map[string]interface{}{"title": "update"},
+		},
+	}
+
+	_, err := BuildCollectionPages("products", CollectionOptions{}, data)
+	if err == nil || !strings.Contains(err.Error(), "both have the slug update") {
+		t.Errorf("expected a duplicate slug error, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kyleterry/solarwind"
+	"github.com/mitchellh/cli"
+)
+
+// checkLinks checks the links of the generated site and prints what it found
+// to ui. It returns false if there are broken links.
+func checkLinks(ui cli.Ui, site *solarwind.Site, external bool) bool {
+	report, err := site.CheckLinks(external)
+	if err != nil {
+		ui.Error(err.Error())
+		return false
+	}
+
+	for _, orphan := range report.Orphans {
+		ui.Warn(fmt.Sprintf("%s: no page links here", orphan))
+	}
+	for _, broken := range report.Broken {
+		ui.Error(broken.String())
+	}
+	if len(report.Broken) > 0 {
+		ui.Error(fmt.Sprintf("Found %d broken links", len(report.Broken)))
+		return false
+	}
+	return true
+}
+
+// CheckCommand code
+type CheckCommand struct {
+	Ui      cli.Ui
+	Plugins []solarwind.Plugin
+}
+
+func (c *CheckCommand) Help() string {
+	helpText := `
+usage: solarwind check [options]
+	Checks the links in the generated site. Every link to another page,
+	static file or #anchor of the site must exist. Pages that no other page
+	links to are reported as orphans. Run solarwind generate first.
+
+	Options:
+		-source "."
+			Path to the project root. By default the current directory and
+			its parents are searched for a Solarwindfile.
+
+		-env "production"
+			Merge Solarwindfile.production on top of the Solarwindfile.
+			Defaults to $SOLARWIND_ENV.
+
+		-external
+			Also check links to other sites by requesting them.
+	`
+	return helpText
+}
+
+func (c *CheckCommand) Synopsis() string {
+	return "Checks the generated site for broken links."
+}
+
+func (c *CheckCommand) Run(args []string) int {
+	var source, env string
+	var external bool
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.StringVar(&source, "source", "", "Path to the project root")
+	flags.StringVar(&env, "env", os.Getenv(solarwind.EnvironmentVariable), "Environment file to merge")
+	flags.BoolVar(&external, "external", false, "Check external links")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	site, err := openSite(source, env, c.Plugins)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if !checkLinks(c.Ui, site, external) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+// Package command is the solarwind command line. cmd/solarwind runs it as is;
+// a binary of your own can run it with plugins, which every command
+// registers with the site it opens:
+//
+//	func main() {
+//		os.Exit(command.Main(wordCounter{}))
+//	}
+package command
+
+import (
+	"log"
+	"os"
+
+	"github.com/kyleterry/solarwind"
+	"github.com/mitchellh/cli"
+)
+
+// Main runs the command named by os.Args and returns its exit code.
+func Main(plugins ...solarwind.Plugin) int {
+	c := cli.NewCLI("solarwind", "0.1.0")
+	ui := &cli.BasicUi{Writer: os.Stdout, ErrorWriter: os.Stderr}
+	c.Args = os.Args[1:]
+	c.Commands = map[string]cli.CommandFactory{
+		"check": func() (cli.Command, error) {
+			return &CheckCommand{
+				Ui:      ui,
+				Plugins: plugins,
+			}, nil
+		},
+		"deploy": func() (cli.Command, error) {
+			return &DeployCommand{
+				Ui:      ui,
+				Plugins: plugins,
+			}, nil
+		},
+		"generate": func() (cli.Command, error) {
+			return &GenerateCommand{
+				Ui:      ui,
+				Plugins: plugins,
+			}, nil
+		},
+		"config": func() (cli.Command, error) {
+			return &ConfigCommand{
+				Ui:      ui,
+				Plugins: plugins,
+			}, nil
+		},
+		"new": func() (cli.Command, error) {
+			return &NewCommand{
+				Ui:      ui,
+				Plugins: plugins,
+			}, nil
+		},
+		"server": func() (cli.Command, error) {
+			return &ServerCommand{
+				Ui:      ui,
+				Plugins: plugins,
+			}, nil
+		},
+	}
+
+	exitCode, err := c.Run()
+	if err != nil {
+		log.Println(err)
+	}
+	return exitCode
+}
+
+// openSite opens the project for a command, logging config warnings and
+// build progress to stderr like the commands always have.
+func openSite(source, env string, plugins []solarwind.Plugin) (*solarwind.Site, error) {
+	site, err := solarwind.Open(source, env, plugins...)
+	if err != nil {
+		return nil, err
+	}
+	site.Logger = log.New(os.Stderr, "", log.LstdFlags)
+	for _, warning := range site.Config.Warnings {
+		site.Logger.Println(warning)
+	}
+	return site, nil
+}
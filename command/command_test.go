@@ -0,0 +1,40 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/kyleterry/solarwind"
+	"github.com/mitchellh/cli"
+)
+
+type afterBuild struct {
+	out string
+}
+
+func (a *afterBuild) AfterBuild(site *solarwind.Site, out string) error {
+	a.out = out
+	return nil
+}
+
+func TestGenerateUsesPlugins(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := solarwind.NewSite(root); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin := &afterBuild{}
+	ui := &cli.MockUi{}
+	c := &GenerateCommand{Ui: ui, Plugins: []solarwind.Plugin{plugin}}
+	if code := c.Run([]string{"-source", root}); code != 0 {
+		t.Fatalf("expected generate to succeed, got %d: %s", code, ui.ErrorWriter.String())
+	}
+	if plugin.out == "" {
+		t.Error("expected the plugin to be called after the build")
+	}
+}
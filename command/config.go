@@ -0,0 +1,57 @@
+package command
+
+import (
+	"flag"
+	"os"
+
+	"github.com/kyleterry/solarwind"
+	"github.com/mitchellh/cli"
+)
+
+// ConfigCommand code
+type ConfigCommand struct {
+	Ui      cli.Ui
+	Plugins []solarwind.Plugin
+}
+
+func (c *ConfigCommand) Help() string {
+	helpText := `
+usage: solarwind config [options]
+	Prints the effective configuration of a solarwind project, including
+	any defaults that were not set in the Solarwindfile.
+
+	Options:
+		-source "."
+			Path to the project root. By default the current directory and
+			its parents are searched for a Solarwindfile.
+
+		-env "production"
+			Merge Solarwindfile.production on top of the Solarwindfile.
+			Defaults to $SOLARWIND_ENV.
+	`
+	return helpText
+}
+
+func (c *ConfigCommand) Synopsis() string {
+	return "Prints the effective site configuration."
+}
+
+func (c *ConfigCommand) Run(args []string) int {
+	var source, env string
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	flags.StringVar(&source, "source", "", "Path to the project root")
+	flags.StringVar(&env, "env", os.Getenv(solarwind.EnvironmentVariable), "Environment file to merge")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	site, err := openSite(source, env, c.Plugins)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	c.Ui.Output(site.Config.String())
+
+	return 0
+}
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"flag"
+	"os"
+
+	"github.com/kyleterry/solarwind"
+	"github.com/mitchellh/cli"
+)
+
+// DeployCommand code
+type DeployCommand struct {
+	Ui      cli.Ui
+	Plugins []solarwind.Plugin
+}
+
+func (c *DeployCommand) Help() string {
+	helpText := `
+usage: solarwind deploy [options]
+	Uploads the generated site to one of the targets under deploy in the
+	Solarwindfile. Only files that changed are uploaded, and files that are
+	no longer part of the site are deleted. Run solarwind generate first.
+
+	-target git commits the site to the gh-pages branch of the project's
+	repository when there is no target called git.
+
+	Options:
+		-target "production"
+			The deploy target to use. It can be left out if there is only
+			one.
+
+		-dry-run
+			Print what would change without changing anything.
+
+		-source "."
+			Path to the project root. By default the current directory and
+			its parents are searched for a Solarwindfile.
+
+		-env "production"
+			Merge Solarwindfile.production on top of the Solarwindfile.
+			Defaults to $SOLARWIND_ENV.
+	`
+	return helpText
+}
+
+func (c *DeployCommand) Synopsis() string {
+	return "Deploys the generated site."
+}
+
+func (c *DeployCommand) Run(args []string) int {
+	var source, env, name string
+	var dryRun bool
+	flags := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	flags.StringVar(&source, "source", "", "Path to the project root")
+	flags.StringVar(&env, "env", os.Getenv(solarwind.EnvironmentVariable), "Environment file to merge")
+	flags.StringVar(&name, "target", "", "Deploy target")
+	flags.BoolVar(&dryRun, "dry-run", false, "Only print what would change")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	site, err := openSite(source, env, c.Plugins)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if err := site.Deploy(&cli.UiWriter{Ui: c.Ui}, name, dryRun); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/kyleterry/solarwind"
+	"github.com/mitchellh/cli"
+)
+
+// GenerateCommand code
+type GenerateCommand struct {
+	Ui      cli.Ui
+	Plugins []solarwind.Plugin
+}
+
+func (c *GenerateCommand) Help() string {
+	helpText := `
+usage: solarwind generate [options]
+	This command will build a solarwind project and put everything in the
+	output directory (./public by default).
+
+	Options:
+		-source "."
+			Path to the project root. By default the current directory and
+			its parents are searched for a Solarwindfile.
+
+		-env "production"
+			Merge Solarwindfile.production on top of the Solarwindfile.
+			Defaults to $SOLARWIND_ENV.
+
+		-drafts
+			Build pages marked as drafts.
+
+		-check
+			Check the generated site for broken links afterwards, like
+			solarwind check does.
+	`
+	return helpText
+}
+
+func (c *GenerateCommand) Synopsis() string {
+	return "Builds a static site from markdown content."
+}
+
+func (c *GenerateCommand) Run(args []string) int {
+	var source, env string
+	var drafts, check bool
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	flags.StringVar(&source, "source", "", "Path to the project root")
+	flags.StringVar(&env, "env", os.Getenv(solarwind.EnvironmentVariable), "Environment file to merge")
+	flags.BoolVar(&drafts, "drafts", false, "Build drafts")
+	flags.BoolVar(&check, "check", false, "Check links after generating")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	site, err := openSite(source, env, c.Plugins)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if drafts {
+		site.Config.Build.Drafts = true
+	}
+
+	if err := site.Build(context.Background(), site.DestinationDir); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if check && !checkLinks(c.Ui, site, false) {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,103 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kyleterry/solarwind"
+	"github.com/mitchellh/cli"
+)
+
+// NewCommand code
+type NewCommand struct {
+	Ui      cli.Ui
+	Plugins []solarwind.Plugin
+}
+
+func (c *NewCommand) Help() string {
+	helpText := `
+usage: solarwind new site <dir>
+       solarwind new post [options] "Post Title"
+
+	new site creates a working solarwind project in <dir> with a
+	Solarwindfile, the starter templates and empty content and static
+	directories.
+
+	new post creates content/posts/<slug>.md in the current project with
+	the header filled in.
+
+	Options for new post:
+		-source "."
+			Path to the project root. By default the current directory and
+			its parents are searched for a Solarwindfile.
+	`
+	return helpText
+}
+
+func (c *NewCommand) Synopsis() string {
+	return "Creates a new site or post."
+}
+
+func (c *NewCommand) Run(args []string) int {
+	if len(args) == 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	switch args[0] {
+	case "site":
+		return c.runSite(args[1:])
+	case "post":
+		return c.runPost(args[1:])
+	}
+
+	c.Ui.Error(fmt.Sprintf("Unknown kind %q, expected site or post.", args[0]))
+	return 1
+}
+
+func (c *NewCommand) runSite(args []string) int {
+	if len(args) != 1 {
+		c.Ui.Error("usage: solarwind new site <dir>")
+		return 1
+	}
+
+	if err := solarwind.NewSite(args[0]); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Created a new site in %s. Run `solarwind generate` there to build it.", args[0]))
+	return 0
+}
+
+func (c *NewCommand) runPost(args []string) int {
+	var source string
+	flags := flag.NewFlagSet("new post", flag.ContinueOnError)
+	flags.StringVar(&source, "source", "", "Path to the project root")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	title := strings.TrimSpace(strings.Join(flags.Args(), " "))
+	if title == "" {
+		c.Ui.Error(`usage: solarwind new post [options] "Post Title"`)
+		return 1
+	}
+
+	site, err := openSite(source, "", c.Plugins)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	p, err := solarwind.NewPost(site.PostsDir, title, time.Now())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Created %s", p))
+	return 0
+}
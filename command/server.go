@@ -0,0 +1,155 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/howeyc/fsnotify"
+	"github.com/kyleterry/solarwind"
+	"github.com/mitchellh/cli"
+)
+
+// watch regenerates the site whenever one of its files changes. Build errors
+// are logged so a typo doesn't take the server down.
+func watch(site *solarwind.Site) error {
+	log.Println("Watching for changes...")
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	// Walking the content directory picks up the posts and the content of
+	// every language.
+	err = filepath.Walk(site.ContentDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Watch(p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range site.TemplateDirs() {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		err = watcher.Watch(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Data, translations and static files are optional, so directories
+	// that can't be walked are skipped.
+	for _, dir := range append([]string{site.DataDir, site.I18nDir}, site.StaticDirs()...) {
+		err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.IsDir() {
+				return watcher.Watch(p)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-watcher.Event:
+			log.Println("Change detected. Regenerating site...")
+			if err := site.Build(context.Background(), site.DestinationDir); err != nil {
+				log.Println("error:", err)
+			}
+		case err := <-watcher.Error:
+			log.Println("error:", err)
+		}
+	}
+}
+
+// ServerCommand code
+type ServerCommand struct {
+	Ui      cli.Ui
+	Plugins []solarwind.Plugin
+}
+
+func (c *ServerCommand) Help() string {
+	helpText := `
+Usage: solarwind server [options]
+	This will watch for changes to files and regenereate the site when those
+	changes are detected.
+
+	Options:
+		-bind ":8090"
+			Binds to a specific address
+
+		-source "."
+			Path to the project root. By default the current directory and
+			its parents are searched for a Solarwindfile.
+
+		-env "production"
+			Merge Solarwindfile.production on top of the Solarwindfile.
+			Defaults to $SOLARWIND_ENV.
+
+		-drafts
+			Build pages marked as drafts.
+	`
+	return helpText
+}
+
+func (c *ServerCommand) Synopsis() string {
+	return "Run a development server."
+}
+
+func (c *ServerCommand) Run(args []string) int {
+	var defaultBind, source, env string
+	var drafts bool
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	flags.StringVar(&defaultBind, "bind", "localhost:8090", "Set an address to bind to")
+	flags.StringVar(&source, "source", "", "Path to the project root")
+	flags.StringVar(&env, "env", os.Getenv(solarwind.EnvironmentVariable), "Environment file to merge")
+	flags.BoolVar(&drafts, "drafts", false, "Build drafts")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	site, err := openSite(source, env, c.Plugins)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if drafts {
+		site.Config.Build.Drafts = true
+	}
+
+	log.Println("About to start development server")
+
+	// Build once up front so the server knows the site's aliases.
+	if err := site.Build(context.Background(), site.DestinationDir); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	go func() {
+		if err := watch(site); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	log.Printf("Server listening on http://%s", defaultBind)
+	err = http.ListenAndServe(defaultBind, site.AliasHandler(http.FileServer(http.Dir(site.DestinationDir))))
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	return 0
+}
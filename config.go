@@ -0,0 +1,585 @@
+package solarwind
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigVersion is the newest Solarwindfile schema this build understands.
+const ConfigVersion = 1
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+const (
+	// EnvPrefix marks environment variables that override Solarwindfile keys.
+	EnvPrefix = "SOLARWIND_"
+
+	// EnvironmentVariable selects an environment file such as
+	// Solarwindfile.production when -env isn't given.
+	EnvironmentVariable = "SOLARWIND_ENV"
+)
+
+const (
+	DefaultSiteTitle       = "Solarwind Site"
+	DefaultSiteDescription = "This is a static site generated with Solarwind: https://github.com/kyleterry/solarwind"
+	DefaultLanguage        = "en"
+)
+
+// Config is the schema of a Solarwindfile. It is exposed to templates as
+// `.Site`, so `params` can be reached with `.Site.Params.whatever`.
+type Config struct {
+	Version         int                          `json:"version"`
+	SiteTitle       string                       `json:"site_title"`
+	SiteDescription string                       `json:"site_description"`
+	BaseURL         string                       `json:"base_url"`
+	Language        string                       `json:"language"`
+	Author          Author                       `json:"author"`
+	Params          map[string]interface{}       `json:"params"`
+	Menus           map[string][]MenuEntry       `json:"menus"`
+	Build           BuildOptions                 `json:"build"`
+	Assets          AssetOptions                 `json:"assets"`
+	Minify          MinifyOptions                `json:"minify"`
+	Images          ImageOptions                 `json:"images"`
+	Collections     map[string]CollectionOptions `json:"collections"`
+	Related         RelatedOptions               `json:"related"`
+	Sections        map[string]SectionOptions    `json:"sections"`
+	Languages       map[string]LanguageOptions   `json:"languages"`
+	Search          SearchOptions                `json:"search"`
+	Redirects       RedirectOptions              `json:"redirects"`
+	Deploy          map[string]DeployOptions     `json:"deploy"`
+	Theme           string                       `json:"theme"`
+	Layout
+
+	// Warnings are the problems LoadConfig and ApplyEnvironment worked
+	// around, like a SOLARWIND_ variable that isn't a config key. They're
+	// left for the caller to report.
+	Warnings []string `json:"-"`
+}
+
+type Author struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	URL   string `json:"url"`
+}
+
+type BuildOptions struct {
+	// PreserveOutput keeps files already in the output directory instead of
+	// wiping it before every build.
+	PreserveOutput bool `json:"preserve_output"`
+
+	// Drafts builds pages with `draft: true` in their header, which are
+	// left out by default.
+	Drafts bool `json:"drafts"`
+
+	// SlugCollisions is what happens when two posts end up with the same
+	// slug: error stops the build and suffix numbers the later ones.
+	SlugCollisions string `json:"slug_collisions"`
+}
+
+// ConfigError describes a Solarwindfile that couldn't be loaded.
+type ConfigError struct {
+	Path    string
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// NewConfig returns a Config with every default filled in.
+func NewConfig() *Config {
+	return &Config{
+		Version:         ConfigVersion,
+		SiteTitle:       DefaultSiteTitle,
+		SiteDescription: DefaultSiteDescription,
+		Language:        DefaultLanguage,
+		Params:          map[string]interface{}{},
+		Menus:           map[string][]MenuEntry{},
+		Assets:          AssetOptions{Minify: true, Fingerprint: true},
+		Minify:          DefaultMinifyOptions,
+		Images:          DefaultImageOptions,
+		Collections:     map[string]CollectionOptions{},
+		Related:         DefaultRelatedOptions,
+		Sections:        map[string]SectionOptions{},
+		Languages:       map[string]LanguageOptions{},
+		Search:          DefaultSearchOptions,
+		Redirects:       DefaultRedirectOptions,
+		Deploy:          map[string]DeployOptions{},
+		Layout:          DefaultLayout,
+	}
+}
+
+// LoadConfig reads and validates the Solarwindfile at path. If env is set and
+// a matching environment file such as Solarwindfile.production exists next to
+// it, that file is merged on top. SOLARWIND_* environment variables are
+// applied last.
+func LoadConfig(path, env string) (*Config, error) {
+	values, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	source := path
+	var warnings []string
+	if env != "" {
+		if envPath, ok := FindSolarwindfile(filepath.Dir(path), Solarwindfile+"."+env); ok {
+			overlay, err := readConfigFile(envPath)
+			if err != nil {
+				return nil, err
+			}
+			mergeConfigValues(values, overlay)
+			source = fmt.Sprintf("%s (with %s)", path, filepath.Base(envPath))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("No %s.%s found, using %s as is", Solarwindfile, env, filepath.Base(path)))
+		}
+	}
+
+	config, err := decodeConfigValues(source, values)
+	if err != nil {
+		return nil, err
+	}
+	config.Warnings = warnings
+
+	if err := config.ApplyEnvironment(os.Environ()); err != nil {
+		return nil, &ConfigError{Path: "environment", Message: err.Error()}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, &ConfigError{Path: source, Message: err.Error()}
+	}
+
+	return config, nil
+}
+
+// ParseConfig decodes a single Solarwindfile on top of the defaults. Unknown
+// keys and values of the wrong type are reported as a *ConfigError naming the
+// key.
+func ParseConfig(path string, content []byte) (*Config, error) {
+	values, err := decodeConfigDocument(path, content)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := decodeConfigValues(path, values)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, &ConfigError{Path: path, Message: err.Error()}
+	}
+
+	return config, nil
+}
+
+// FindSolarwindfile looks in dir for name or name with one of the supported
+// config extensions.
+func FindSolarwindfile(dir, name string) (string, bool) {
+	for _, ext := range []string{"", ".json", ".yaml", ".yml", ".toml"} {
+		p := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// DetectConfigFormat works out whether a Solarwindfile is JSON, YAML or TOML,
+// first by extension and then by looking at the content.
+func DetectConfigFormat(path string, content []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 || trimmed[0] == '{' {
+		return FormatJSON
+	}
+	if tomlLine.Match(content) {
+		return FormatTOML
+	}
+	return FormatYAML
+}
+
+// tomlLine matches a TOML table or key at the start of a line. Indented lines
+// are left out since YAML block scalars and flow sequences look the same.
+var tomlLine = regexp.MustCompile(`(?m)^(\[[^\]]+\]|[A-Za-z0-9_-]+[ \t]*=)`)
+
+func readConfigFile(path string) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConfigDocument(path, content)
+}
+
+// decodeConfigDocument parses a JSON, YAML or TOML document into plain maps
+// and slices so documents of any format can be merged together.
+func decodeConfigDocument(path string, content []byte) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return values, nil
+	}
+
+	switch DetectConfigFormat(path, content) {
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return nil, &ConfigError{Path: path, Message: describeJSONError(content, err)}
+		}
+	case FormatYAML:
+		var doc interface{}
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, &ConfigError{Path: path, Message: err.Error()}
+		}
+		m, ok := normalizeConfigValue(doc).(map[string]interface{})
+		if !ok {
+			return nil, &ConfigError{Path: path, Message: "expected a mapping at the top level"}
+		}
+		values = m
+	case FormatTOML:
+		if _, err := toml.Decode(string(content), &values); err != nil {
+			return nil, &ConfigError{Path: path, Message: err.Error()}
+		}
+		values = normalizeConfigValue(values).(map[string]interface{})
+	}
+
+	return values, nil
+}
+
+// decodeConfigValues strictly decodes merged config values onto the defaults.
+func decodeConfigValues(path string, values map[string]interface{}) (*Config, error) {
+	config := NewConfig()
+	if err := decodeStrict(path, values, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// decodeStrict decodes values into v, rejecting unknown keys and values of
+// the wrong type.
+func decodeStrict(path string, values map[string]interface{}, v interface{}) error {
+	content, err := json.Marshal(values)
+	if err != nil {
+		return &ConfigError{Path: path, Message: err.Error()}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return &ConfigError{Path: path, Message: describeJSONError(content, err)}
+	}
+	return nil
+}
+
+// normalizeConfigValue turns the map types produced by the YAML and TOML
+// decoders into map[string]interface{} and []interface{}.
+func normalizeConfigValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeConfigValue(item)
+		}
+		return m
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeConfigValue(item)
+		}
+		return v
+	case []map[string]interface{}:
+		l := make([]interface{}, len(v))
+		for i, item := range v {
+			l[i] = normalizeConfigValue(item)
+		}
+		return l
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeConfigValue(item)
+		}
+		return v
+	}
+	return value
+}
+
+// mergeConfigValues merges src into dst. Nested objects are merged key by key;
+// everything else, lists included, is replaced.
+func mergeConfigValues(dst, src map[string]interface{}) {
+	for key, value := range src {
+		if srcMap, ok := value.(map[string]interface{}); ok {
+			if dstMap, ok := dst[key].(map[string]interface{}); ok {
+				mergeConfigValues(dstMap, srcMap)
+				continue
+			}
+		}
+		dst[key] = value
+	}
+}
+
+// ApplyEnvironment overrides config keys with SOLARWIND_* variables from
+// environ. The variable name is the upper-cased key, with nested keys
+// separated by a double underscore: SOLARWIND_BASE_URL sets base_url and
+// SOLARWIND_PARAMS__TWITTER sets params.twitter. Only mistakes inside an
+// object like build or params are errors. Anything else that can't be set,
+// like a CI system's SOLARWIND_VERSION=1.2.3, is skipped and added to
+// Warnings.
+func (c *Config) ApplyEnvironment(environ []string) error {
+	for _, kv := range environ {
+		if !strings.HasPrefix(kv, EnvPrefix) {
+			continue
+		}
+
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == EnvironmentVariable {
+			continue
+		}
+
+		keys := strings.Split(strings.ToLower(strings.TrimPrefix(parts[0], EnvPrefix)), "__")
+		field, ok := fieldByJSONKey(reflect.ValueOf(c).Elem(), keys[0])
+		if !ok {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("Ignoring %s: %s is not a config key", parts[0], keys[0]))
+			continue
+		}
+		if err := setConfigKey(field, keys[1:], parts[1]); err != nil {
+			if kind := field.Kind(); kind != reflect.Struct && kind != reflect.Map {
+				c.Warnings = append(c.Warnings, fmt.Sprintf("Ignoring %s: %s", parts[0], err))
+				continue
+			}
+			return fmt.Errorf("%s: %s", parts[0], err)
+		}
+	}
+	return nil
+}
+
+func setConfigKey(v reflect.Value, keys []string, value string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		if len(keys) == 0 {
+			return fmt.Errorf("is an object, set one of its keys instead")
+		}
+		field, ok := fieldByJSONKey(v, keys[0])
+		if !ok {
+			return fmt.Errorf("unknown key %q", keys[0])
+		}
+		return setConfigKey(field, keys[1:], value)
+	case reflect.Map:
+		if len(keys) != 1 || v.Type().Elem().Kind() != reflect.Interface {
+			return fmt.Errorf("can't be set from the environment")
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		v.SetMapIndex(reflect.ValueOf(keys[0]), reflect.ValueOf(value))
+		return nil
+	}
+
+	if len(keys) != 0 {
+		return fmt.Errorf("unknown key %q", keys[0])
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("can't be set from the environment")
+	}
+	return nil
+}
+
+// fieldByJSONKey finds the struct field tagged with key, looking through
+// embedded structs the way encoding/json does.
+func fieldByJSONKey(v reflect.Value, key string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if f, ok := fieldByJSONKey(v.Field(i), key); ok {
+				return f, true
+			}
+			continue
+		}
+		if strings.Split(field.Tag.Get("json"), ",")[0] == key {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// Validate checks the values that can't be expressed by the schema alone.
+func (c *Config) Validate() error {
+	if c.Version < 1 || c.Version > ConfigVersion {
+		return fmt.Errorf("unsupported version %d (this build supports up to %d)", c.Version, ConfigVersion)
+	}
+
+	if c.BaseURL != "" {
+		u, err := url.Parse(c.BaseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("base_url %q must be an absolute URL such as https://example.com/", c.BaseURL)
+		}
+	}
+
+	if c.Images.Quality < 1 || c.Images.Quality > 100 {
+		return fmt.Errorf("images.quality must be between 1 and 100")
+	}
+	if _, ok := imageExtensions[c.Images.Format]; c.Images.Format != "" && !ok {
+		return fmt.Errorf("images.format %q must be one of jpeg, png or gif", c.Images.Format)
+	}
+	if _, err := parseColor(c.Images.Background); err != nil {
+		return fmt.Errorf("images.background: %s", err)
+	}
+	for _, width := range c.Images.Widths {
+		if width <= 0 {
+			return fmt.Errorf("images.widths must be positive, not %d", width)
+		}
+	}
+
+	if _, ok := c.Languages[c.Language]; c.Multilingual() && !ok {
+		return fmt.Errorf("language %q must be one of the languages", c.Language)
+	}
+
+	switch c.Build.SlugCollisions {
+	case "", SlugCollisionsError, SlugCollisionsSuffix:
+	default:
+		return fmt.Errorf("build.slug_collisions %q must be error or suffix", c.Build.SlugCollisions)
+	}
+
+	for _, field := range c.Search.Fields {
+		switch field {
+		case SearchFieldTitle, SearchFieldTags, SearchFieldSummary, SearchFieldText:
+		default:
+			return fmt.Errorf("search.fields %q must be one of title, tags, summary or text", field)
+		}
+	}
+	if c.Search.Enabled && c.Search.Output == "" {
+		return fmt.Errorf("search.output is missing")
+	}
+
+	for name, target := range c.Deploy {
+		switch {
+		case target.Type == DeployTypeS3 && target.Bucket == "":
+			return fmt.Errorf("deploy.%s.bucket is missing", name)
+		case target.Type == DeployTypeDir && target.Path == "":
+			return fmt.Errorf("deploy.%s.path is missing", name)
+		case target.Type != DeployTypeS3 && target.Type != DeployTypeDir && target.Type != DeployTypeGit:
+			return fmt.Errorf("deploy.%s.type %q must be s3, dir or git", name, target.Type)
+		}
+	}
+
+	for name, options := range c.Collections {
+		if p := options.withDefaults(name).Path; p == ".." || strings.HasPrefix(p, "../") {
+			return fmt.Errorf("collections.%s.path %q must stay inside the output directory", name, options.Path)
+		}
+	}
+
+	for name, section := range c.Sections {
+		if section.Order != "" && section.Order != SortAscending && section.Order != SortDescending {
+			return fmt.Errorf("sections.%s.order %q must be asc or desc", name, section.Order)
+		}
+	}
+
+	for name, entries := range c.Menus {
+		for i, entry := range entries {
+			if entry.Name == "" {
+				return fmt.Errorf("menus.%s[%d] is missing a name", name, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// String renders the config as indented JSON, with deploy secrets masked so
+// it's safe to paste into logs and issues.
+func (c *Config) String() string {
+	masked := *c
+	masked.Deploy = make(map[string]DeployOptions, len(c.Deploy))
+	for name, target := range c.Deploy {
+		if target.SecretAccessKey != "" {
+			target.SecretAccessKey = "********"
+		}
+		masked.Deploy[name] = target
+	}
+	b, err := json.MarshalIndent(&masked, "", "    ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+func describeJSONError(content []byte, err error) string {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		line, col := lineAndColumn(content, e.Offset)
+		return fmt.Sprintf("syntax error at line %d, column %d: %s", line, col, e.Error())
+	case *json.UnmarshalTypeError:
+		if e.Field != "" {
+			return fmt.Sprintf("%q must be a %s, not a %s", e.Field, describeType(e.Type.Kind().String()), e.Value)
+		}
+		return fmt.Sprintf("expected an object, not a %s", e.Value)
+	}
+
+	// encoding/json has no typed error for unknown fields
+	msg := err.Error()
+	if strings.HasPrefix(msg, "json: unknown field ") {
+		return fmt.Sprintf("unknown key %s", strings.TrimPrefix(msg, "json: unknown field "))
+	}
+	return msg
+}
+
+func describeType(kind string) string {
+	switch kind {
+	case "int", "int64", "float64":
+		return "number"
+	case "bool":
+		return "boolean"
+	case "map", "struct":
+		return "object"
+	case "slice":
+		return "list"
+	}
+	return kind
+}
+
+func lineAndColumn(content []byte, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndex(before, []byte("\n"))
+	return line, col - 1
+}
@@ -0,0 +1,160 @@
+package solarwind
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConfigFillsDefaults(t *testing.T) {
+	config, err := ParseConfig(Solarwindfile, []byte(`{"site_title": "My Site", "params": {"twitter": "@me"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.SiteTitle != "My Site" {
+		t.Errorf("expected site title to be parsed, got %q", config.SiteTitle)
+	}
+	if config.SiteDescription != DefaultSiteDescription {
+		t.Errorf("expected default description, got %q", config.SiteDescription)
+	}
+	if config.ContentDir != DefaultLayout.ContentDir {
+		t.Errorf("expected default content dir, got %q", config.ContentDir)
+	}
+	if config.Params["twitter"] != "@me" {
+		t.Errorf("expected params to be parsed, got %v", config.Params)
+	}
+}
+
+func TestParseConfigRejectsBadInput(t *testing.T) {
+	cases := []struct {
+		content string
+		message string
+	}{
+		{`{"site-title": "My Site"}`, `unknown key "site-title"`},
+		{`{"site_title": 42}`, `"site_title" must be a string, not a number`},
+		{"{\n  \"site_title\": \"a\",\n}", "line 3"},
+		{`{"version": 99}`, "unsupported version 99"},
+		{`{"base_url": "example.com"}`, "must be an absolute URL"},
+		{`{"images": {"background": "white"}}`, "images.background"},
+		{`{"collections": {"products": {"path": "../elsewhere"}}}`, "must stay inside the output directory"},
+		{`{"collections": {"products": {"path": "shop/../.."}}}`, "must stay inside the output directory"},
+	}
+
+	for _, c := range cases {
+		_, err := ParseConfig(Solarwindfile, []byte(c.content))
+		if err == nil {
+			t.Errorf("expected %s to fail", c.content)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.message) {
+			t.Errorf("expected error for %s to mention %q, got %q", c.content, c.message, err)
+		}
+	}
+}
+
+func TestParseConfigAcceptsYAMLAndTOML(t *testing.T) {
+	cases := []struct {
+		path    string
+		content string
+	}{
+		{"Solarwindfile.yaml", "site_title: My Site\nmenus:\n  main:\n    - name: About\n      weight: 1\n"},
+		{"Solarwindfile", "site_title: My Site\nmenus:\n  main:\n    - name: About\n      weight: 1\n"},
+		{"Solarwindfile.toml", "site_title = \"My Site\"\n[[menus.main]]\nname = \"About\"\nweight = 1\n"},
+		{"Solarwindfile", "site_title = \"My Site\"\n[[menus.main]]\nname = \"About\"\nweight = 1\n"},
+		{"Solarwindfile", "site_title: My Site\nparams:\n  formula: |\n    e = mc2\nmenus:\n  main:\n    - name: About\n      weight: 1\n"},
+		{"Solarwindfile", "site_title: My Site\nparams:\n  colours:\n    [red, blue]\nmenus:\n  main:\n    - name: About\n      weight: 1\n"},
+	}
+
+	for _, c := range cases {
+		config, err := ParseConfig(c.path, []byte(c.content))
+		if err != nil {
+			t.Errorf("%s: %s", c.path, err)
+			continue
+		}
+		if config.SiteTitle != "My Site" || len(config.Menus["main"]) != 1 || config.Menus["main"][0].Weight != 1 {
+			t.Errorf("%s: unexpected config %+v", c.path, config)
+		}
+	}
+}
+
+func TestLoadConfigMergesEnvironmentFileAndVariables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := "site_title: My Site\nbase_url: http://localhost:8090/\nparams:\n  twitter: \"@me\"\n  github: me\n"
+	production := "base_url = \"https://example.com/\"\n[params]\ntwitter = \"@us\"\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, Solarwindfile), []byte(base), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, Solarwindfile+".production"), []byte(production), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("SOLARWIND_SITE_DESCRIPTION", "From the environment")
+	os.Setenv("SOLARWIND_BUILD__PRESERVE_OUTPUT", "true")
+	defer os.Unsetenv("SOLARWIND_SITE_DESCRIPTION")
+	defer os.Unsetenv("SOLARWIND_BUILD__PRESERVE_OUTPUT")
+
+	config, err := LoadConfig(filepath.Join(dir, Solarwindfile), "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.BaseURL != "https://example.com/" {
+		t.Errorf("expected production base_url, got %q", config.BaseURL)
+	}
+	if config.Params["twitter"] != "@us" || config.Params["github"] != "me" {
+		t.Errorf("expected params to be merged, got %v", config.Params)
+	}
+	if config.SiteDescription != "From the environment" || !config.Build.PreserveOutput {
+		t.Errorf("expected environment overrides, got %+v", config)
+	}
+}
+
+func TestApplyEnvironmentUnknownKeys(t *testing.T) {
+	logged := &bytes.Buffer{}
+	log.SetOutput(logged)
+	defer log.SetOutput(os.Stderr)
+
+	config := NewConfig()
+	if err := config.ApplyEnvironment([]string{"SOLARWIND_VERSION=1.2.3", "SOLARWIND_BASE_ULR=https://example.com/"}); err != nil {
+		t.Errorf("expected variables that aren't config keys to be skipped, got %v", err)
+	}
+	if config.BaseURL != NewConfig().BaseURL {
+		t.Errorf("expected base_url to be left alone, got %q", config.BaseURL)
+	}
+	if len(config.Warnings) != 2 || !strings.Contains(config.Warnings[1], "SOLARWIND_BASE_ULR") {
+		t.Errorf("expected a warning for each variable, got %q", config.Warnings)
+	}
+	if logged.Len() != 0 {
+		t.Errorf("expected warnings to be left to the caller, got %q", logged)
+	}
+
+	err := NewConfig().ApplyEnvironment([]string{"SOLARWIND_BUILD__DARFTS=true"})
+	if err == nil || !strings.Contains(err.Error(), `unknown key "darfts"`) {
+		t.Errorf("expected an unknown key error, got %v", err)
+	}
+}
+
+func TestConfigStringMasksSecrets(t *testing.T) {
+	config := NewConfig()
+	config.Deploy = map[string]DeployOptions{
+		"production": {Type: DeployTypeS3, Bucket: "site", AccessKeyID: "AKID", SecretAccessKey: "s3cr3t"},
+	}
+
+	s := config.String()
+	if strings.Contains(s, "s3cr3t") || !strings.Contains(s, `"secret_access_key": "********"`) {
+		t.Errorf("expected the secret to be masked in:\n%s", s)
+	}
+	if config.Deploy["production"].SecretAccessKey != "s3cr3t" {
+		t.Error("expected String to leave the config alone")
+	}
+}
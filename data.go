@@ -0,0 +1,138 @@
+package solarwind
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// LoadData reads every JSON, YAML, TOML and CSV file under dir. The result is
+// exposed to templates as `.Site.Data`: data/team.yaml becomes
+// .Site.Data.team and data/talks/2015.json becomes .Site.Data.talks.2015 (use
+// `index .Site.Data.talks "2015"` for keys that aren't identifiers).
+//
+// CSV files become a list of rows, each a map from the header row's column
+// names to the row's values. A missing dir is not an error.
+func LoadData(dir string) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return data, nil
+	}
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+
+		ext := strings.ToLower(path.Ext(p))
+		if !IsDataFile(ext) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		keys := strings.Split(filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel))), "/")
+
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		value, err := decodeDataFile(p, ext, content)
+		if err != nil {
+			return err
+		}
+
+		return setDataValue(data, keys, value, rel)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func IsDataFile(ext string) bool {
+	switch ext {
+	case ".json", ".yaml", ".yml", ".toml", ".csv":
+		return true
+	}
+	return false
+}
+
+func setDataValue(data map[string]interface{}, keys []string, value interface{}, file string) error {
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := data[key]
+		if !ok {
+			next = map[string]interface{}{}
+			data[key] = next
+		}
+		m, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("data file %s clashes with another data file called %s", file, key)
+		}
+		data = m
+	}
+
+	key := keys[len(keys)-1]
+	if _, ok := data[key]; ok {
+		return fmt.Errorf("data file %s clashes with another data file or directory called %s", file, key)
+	}
+	data[key] = value
+	return nil
+}
+
+func decodeDataFile(p, ext string, content []byte) (interface{}, error) {
+	switch ext {
+	case ".json":
+		var value interface{}
+		if err := json.Unmarshal(content, &value); err != nil {
+			return nil, &ConfigError{Path: p, Message: describeJSONError(content, err)}
+		}
+		return value, nil
+	case ".yaml", ".yml":
+		var value interface{}
+		if err := yaml.Unmarshal(content, &value); err != nil {
+			return nil, &ConfigError{Path: p, Message: err.Error()}
+		}
+		return normalizeConfigValue(value), nil
+	case ".toml":
+		value := map[string]interface{}{}
+		if _, err := toml.Decode(string(content), &value); err != nil {
+			return nil, &ConfigError{Path: p, Message: err.Error()}
+		}
+		return normalizeConfigValue(value), nil
+	case ".csv":
+		records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+		if err != nil {
+			return nil, &ConfigError{Path: p, Message: err.Error()}
+		}
+		rows := []interface{}{}
+		if len(records) == 0 {
+			return rows, nil
+		}
+		header := records[0]
+		for _, record := range records[1:] {
+			row := make(map[string]interface{}, len(header))
+			for i, column := range header {
+				row[column] = record[i]
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("%s is not a data file", p)
+}
@@ -0,0 +1,72 @@
+package solarwind
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadDataNestsDirectories(t *testing.T) {
+	dir, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFiles(t, dir, map[string]string{
+		"team.yaml":        "- name: Kyle\n  role: Owl drawer\n",
+		"talks/2015.json":  `[{"title": "Solarwind"}]`,
+		"talks/venues.csv": "name,city\nGopherCon,Denver\n",
+		"site.toml":        "[social]\ntwitter = \"@me\"\n",
+		"README.md":        "not data",
+	})
+
+	data, err := LoadData(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"team": []interface{}{map[string]interface{}{"name": "Kyle", "role": "Owl drawer"}},
+		"talks": map[string]interface{}{
+			"2015":   []interface{}{map[string]interface{}{"title": "Solarwind"}},
+			"venues": []interface{}{map[string]interface{}{"name": "GopherCon", "city": "Denver"}},
+		},
+		"site": map[string]interface{}{"social": map[string]interface{}{"twitter": "@me"}},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("expected %v, got %v", expected, data)
+	}
+}
+
+func TestLoadDataRejectsClashes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFiles(t, dir, map[string]string{
+		"team.yaml": "[]",
+		"team.json": "[]",
+	})
+
+	if _, err := LoadData(dir); err == nil || !strings.Contains(err.Error(), "clashes") {
+		t.Errorf("expected a clash error, got %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+package solarwind
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	DeployTypeS3  = "s3"
+	DeployTypeDir = "dir"
+	DeployTypeGit = "git"
+)
+
+// DeployOptions describes one place the site can be deployed to. Targets are
+// configured by name under `deploy`.
+type DeployOptions struct {
+	// Type is s3, dir or git.
+	Type string `json:"type"`
+
+	// Bucket, Region, Endpoint and Prefix locate an S3 compatible bucket.
+	// Endpoint defaults to AWS in Region, and Prefix is put in front of
+	// every key.
+	Bucket   string `json:"bucket"`
+	Region   string `json:"region"`
+	Endpoint string `json:"endpoint"`
+	Prefix   string `json:"prefix"`
+
+	// PathStyle puts the bucket in the path of the URL instead of the host
+	// name, which is what most self-hosted stores like MinIO want.
+	PathStyle bool `json:"path_style"`
+
+	// AccessKeyID and SecretAccessKey default to $AWS_ACCESS_KEY_ID and
+	// $AWS_SECRET_ACCESS_KEY, which keep the secret out of the Solarwindfile.
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+
+	// Path is the directory a dir target copies the site into.
+	Path string `json:"path"`
+
+	// Repo is the git repository a git target commits to, which defaults
+	// to the project's own. A relative Repo is relative to the project. Branch defaults to gh-pages. The branch is
+	// pushed to Remote after every deploy if it's set, and CNAME puts a
+	// CNAME file with the custom domain of the site at its root.
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+	Remote string `json:"remote"`
+	CNAME  string `json:"cname"`
+
+	// CacheControl sets the Cache-Control header of matching files. The
+	// first rule that matches a file wins.
+	CacheControl []CacheRule `json:"cache_control"`
+
+	// KeepRemoved leaves files that are no longer in the site alone instead
+	// of deleting them.
+	KeepRemoved bool `json:"keep_removed"`
+}
+
+// CacheRule matches files by path. Patterns ending in a slash match
+// everything under that directory, patterns with a slash are matched against
+// the whole path and anything else against the file name.
+type CacheRule struct {
+	Match        string `json:"match"`
+	CacheControl string `json:"cache_control"`
+}
+
+func (r CacheRule) Matches(file string) bool {
+	switch {
+	case strings.HasSuffix(r.Match, "/"):
+		return strings.HasPrefix(file, r.Match)
+	case strings.Contains(r.Match, "/"):
+		ok, _ := path.Match(r.Match, file)
+		return ok
+	}
+	ok, _ := path.Match(r.Match, path.Base(file))
+	return ok
+}
+
+// DeployFile is a file of the generated site.
+type DeployFile struct {
+	// Path is slash separated and relative to the output directory.
+	Path         string
+	Hash         string
+	ContentType  string
+	CacheControl string
+
+	// Source is where the file is on disk.
+	Source string
+}
+
+// DeployTarget is somewhere the site can be deployed to.
+type DeployTarget interface {
+	// List returns the MD5 hash of every file at the target by path.
+	List() (map[string]string, error)
+	Upload(file DeployFile) error
+	Delete(path string) error
+}
+
+// DeployPlan is what a deploy is going to change.
+type DeployPlan struct {
+	Upload    []DeployFile
+	Delete    []string
+	Unchanged int
+}
+
+// NewDeployTarget returns the target options describe.
+func NewDeployTarget(options DeployOptions) (DeployTarget, error) {
+	switch options.Type {
+	case DeployTypeS3:
+		return NewS3Target(options), nil
+	case DeployTypeDir:
+		return &DirTarget{Dir: options.Path}, nil
+	}
+	return nil, fmt.Errorf("unknown deploy type %q", options.Type)
+}
+
+// LocalFiles lists every file in dir with its hash and headers.
+func LocalFiles(dir string, options DeployOptions) ([]DeployFile, error) {
+	var files []DeployFile
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		file := DeployFile{
+			Path:        filepath.ToSlash(rel),
+			Hash:        md5Hex(content),
+			ContentType: mime.TypeByExtension(path.Ext(p)),
+			Source:      p,
+		}
+		if file.ContentType == "" {
+			file.ContentType = http.DetectContentType(content)
+		}
+		for _, rule := range options.CacheControl {
+			if rule.Matches(file.Path) {
+				file.CacheControl = rule.CacheControl
+				break
+			}
+		}
+		files = append(files, file)
+		return nil
+	})
+	return files, err
+}
+
+// PlanDeploy compares the local files with the hashes at the target.
+func PlanDeploy(local []DeployFile, remote map[string]string, keepRemoved bool) *DeployPlan {
+	plan := &DeployPlan{}
+	seen := map[string]bool{}
+	for _, file := range local {
+		seen[file.Path] = true
+		if remote[file.Path] == file.Hash {
+			plan.Unchanged++
+			continue
+		}
+		plan.Upload = append(plan.Upload, file)
+	}
+
+	if !keepRemoved {
+		for p := range remote {
+			if !seen[p] {
+				plan.Delete = append(plan.Delete, p)
+			}
+		}
+		sort.Strings(plan.Delete)
+	}
+	return plan
+}
+
+// Deploy makes the target match the files in dir, reporting every change to
+// w. With dryRun nothing is changed.
+func Deploy(w io.Writer, target DeployTarget, dir string, options DeployOptions, dryRun bool) error {
+	local, err := LocalFiles(dir, options)
+	if err != nil {
+		return err
+	}
+	remote, err := target.List()
+	if err != nil {
+		return err
+	}
+	plan := PlanDeploy(local, remote, options.KeepRemoved)
+
+	for _, file := range plan.Upload {
+		fmt.Fprintf(w, "upload %s\n", file.Path)
+		if !dryRun {
+			if err := target.Upload(file); err != nil {
+				return fmt.Errorf("could not upload %s: %s", file.Path, err)
+			}
+		}
+	}
+	for _, p := range plan.Delete {
+		fmt.Fprintf(w, "delete %s\n", p)
+		if !dryRun {
+			if err := target.Delete(p); err != nil {
+				return fmt.Errorf("could not delete %s: %s", p, err)
+			}
+		}
+	}
+
+	summary := fmt.Sprintf("%d uploaded, %d deleted, %d unchanged", len(plan.Upload), len(plan.Delete), plan.Unchanged)
+	if dryRun {
+		summary = "Dry run: " + summary
+	}
+	fmt.Fprintln(w, summary)
+	return nil
+}
+
+func md5Hex(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// DirTarget deploys into a directory, like rsync would.
+type DirTarget struct {
+	Dir string
+}
+
+func (t *DirTarget) List() (map[string]string, error) {
+	files := map[string]string{}
+	if _, err := os.Stat(t.Dir); os.IsNotExist(err) {
+		return files, nil
+	}
+	err := filepath.Walk(t.Dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(t.Dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = md5Hex(content)
+		return nil
+	})
+	return files, err
+}
+
+func (t *DirTarget) Upload(file DeployFile) error {
+	content, err := ioutil.ReadFile(file.Source)
+	if err != nil {
+		return err
+	}
+	dest := filepath.Join(t.Dir, filepath.FromSlash(file.Path))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dest, content, 0644)
+}
+
+func (t *DirTarget) Delete(p string) error {
+	return os.Remove(filepath.Join(t.Dir, filepath.FromSlash(p)))
+}
+
+// Deploy uploads the generated site to the deploy target called name, or to
+// the only one if name is empty. A target called git that isn't configured
+// commits to the gh-pages branch of the project's repository.
+func (s *Site) Deploy(w io.Writer, name string, dryRun bool) error {
+	options, err := findDeployTarget(s.Config.Deploy, name)
+	if err != nil {
+		return err
+	}
+	if options.Type == DeployTypeGit {
+		if options.Repo != "" && !filepath.IsAbs(options.Repo) {
+			options.Repo = filepath.Join(s.Root, options.Repo)
+		}
+		return NewGitTarget(options, s.Root).Deploy(w, s.DestinationDir, dryRun)
+	}
+	if options.Type == DeployTypeDir {
+		if options.Path, err = s.deployDir(options.Path); err != nil {
+			return err
+		}
+	}
+
+	target, err := NewDeployTarget(options)
+	if err != nil {
+		return err
+	}
+	return Deploy(w, target, s.DestinationDir, options, dryRun)
+}
+
+// deployDir resolves the path of a dir target against the project root. Files
+// at the target that aren't part of the site are deleted, so it may not hold
+// the project or the output directory.
+func (s *Site) deployDir(dir string) (string, error) {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.Root, dir)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	root, err := filepath.Abs(s.Root)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range []string{root, s.DestinationDir} {
+		if pathContains(dir, p) {
+			return "", fmt.Errorf("can't deploy to %s, it would delete %s", dir, p)
+		}
+	}
+	return dir, nil
+}
+
+func findDeployTarget(targets map[string]DeployOptions, name string) (DeployOptions, error) {
+	if name == "" {
+		if len(targets) == 1 {
+			for _, options := range targets {
+				return options, nil
+			}
+		}
+		names := make([]string, 0, len(targets))
+		for n := range targets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return DeployOptions{}, fmt.Errorf("there are no deploy targets in the Solarwindfile")
+		}
+		return DeployOptions{}, fmt.Errorf("pick a deploy target with -target: %s", strings.Join(names, ", "))
+	}
+
+	options, ok := targets[name]
+	if !ok && name == DeployTypeGit {
+		// A git target works without any configuration.
+		return DeployOptions{Type: DeployTypeGit}, nil
+	}
+	if !ok {
+		return DeployOptions{}, fmt.Errorf("there is no deploy target called %s", name)
+	}
+	return options, nil
+}
@@ -1,22 +1,21 @@
-package main
+package solarwind
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"html/template"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/extemporalgenome/slug"
-	"github.com/mitchellh/cli"
 	"github.com/russross/blackfriday"
 )
 
@@ -26,6 +25,11 @@ const (
 	TypeHTML         = "html"
 )
 
+// markdownPageTemplate fills in the blocks page.html expects for pages
+// written in markdown.
+const markdownPageTemplate = `{{define "content"}}{{ .CurrentPage.FinalHTML }}{{end}}` +
+	`{{define "site-title"}}{{ .SiteTitle }}{{ .CurrentPage.Title }}{{end}}`
+
 type Posts []MarkdownPage
 
 type FileMapper struct {
@@ -36,12 +40,23 @@ type FileMapper struct {
 }
 
 type Context struct {
-	SiteTitle       string `json:"site_title"`
-	SiteDescription string `json:"site_description"`
+	SiteTitle       string
+	SiteDescription string
+	Site            *SiteContext
 	Posts           *Posts
 	CurrentPage     MarkdownPage
 }
 
+// SiteContext is what templates see as `.Site`: the Solarwindfile plus
+// everything worked out from the content during a build.
+type SiteContext struct {
+	*Config
+	Menus       Menus
+	Data        map[string]interface{}
+	Collections map[string][]MarkdownPage
+	Languages   []*Language
+}
+
 type Page interface {
 	GetType() string
 	GetFinalHTML() template.HTML
@@ -53,11 +68,25 @@ type MarkdownPage struct {
 	Slug            string
 	Date            time.Time
 	Category        string
+	Tags            []string
+	Aliases         []string
+	Draft           bool
+	Weight          int
 	Filename        string
 	DestinationFile string
 	RelLink         string
-	RawMarkdown     string        // This is the Markdown sans header
-	FinalHTML       template.HTML // This is the final HTML after the Markdown parser
+	Menu            PageMenu
+	Params          map[string]interface{} // Unknown header fields, or a data collection entry
+	Related         []*MarkdownPage        // Posts sharing tags or a category, best match first
+	Language        string                 // The code of the language the page is in
+	Translations    []*MarkdownPage        // The same page in the site's other languages
+	Prev            *MarkdownPage          // The page before this one in its section
+	Next            *MarkdownPage          // The page after this one in its section
+	RawMarkdown     string                 // This is the Markdown sans header
+	FinalHTML       template.HTML          // This is the final HTML after the Markdown parser
+	terms           map[string]neighbours
+	translationKey  string
+	sourceFile      string
 }
 
 type HTMLPage struct {
@@ -101,15 +130,17 @@ func (p HTMLPage) GetTitle() string {
 // title: this is a post title
 // date: 2015-03-20 15:35 PDT
 // category: computers
+// tags: go, static sites
+// draft: true
+// weight: 10
+// aliases: /posts/old-title.html
+// menu: main
 // ###
 //
 // This will parse out the header and return a new MarkdownPage instance with
-// the header fields and raw Markdown content sans-header.
-//
-// :kyleterry: TODO: thing about returning an error here if something goes wrong
-// during parsing of the header. Currently `log.Fatal`s.
-func NewMarkdownPage(filename string, rawContent string) MarkdownPage {
-	log.Printf("Parsing %s", filename)
+// the header fields and raw Markdown content sans-header. The page is laid out
+// as a post at the root of the output directory.
+func NewMarkdownPage(filename string, rawContent string) (MarkdownPage, error) {
 	sd := strings.Split(rawContent, "\n")
 	page := MarkdownPage{}
 	page.Filename = filename
@@ -122,6 +153,9 @@ func NewMarkdownPage(filename string, rawContent string) MarkdownPage {
 			}
 
 			sl := strings.SplitN(line, ":", 2)
+			if len(sl) != 2 {
+				return page, fmt.Errorf("Malformed header line %d in %s: %q is not a key: value pair.", index+2, filename, line)
+			}
 			sl[1] = strings.Trim(sl[1], " ")
 			switch sl[0] {
 			case "title":
@@ -129,56 +163,93 @@ func NewMarkdownPage(filename string, rawContent string) MarkdownPage {
 			case "date":
 				parsedTime, err := time.Parse(time.RFC822, sl[1])
 				if err != nil {
-					log.Fatal("Malformed date string: can't parse date.")
+					return page, fmt.Errorf("Malformed date in %s: can't parse date.", filename)
 				}
 				page.Date = parsedTime
 			case "category":
 				page.Category = sl[1]
+			case "tags":
+				for _, tag := range strings.Split(sl[1], ",") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						page.Tags = append(page.Tags, tag)
+					}
+				}
+			case "aliases":
+				for _, alias := range strings.Split(sl[1], ",") {
+					if alias = strings.TrimSpace(alias); alias != "" {
+						page.Aliases = append(page.Aliases, alias)
+					}
+				}
+			case "draft":
+				draft, err := strconv.ParseBool(sl[1])
+				if err != nil {
+					return page, fmt.Errorf("Malformed draft in %s: must be true or false.", filename)
+				}
+				page.Draft = draft
+			case "menu":
+				for _, name := range strings.Split(sl[1], ",") {
+					if name = strings.TrimSpace(name); name != "" {
+						page.Menu.Menus = append(page.Menu.Menus, name)
+					}
+				}
+			case "menu_name":
+				page.Menu.Name = sl[1]
+			case "menu_identifier":
+				page.Menu.Identifier = sl[1]
+			case "menu_parent":
+				page.Menu.Parent = sl[1]
+			case "menu_weight":
+				weight, err := strconv.Atoi(sl[1])
+				if err != nil {
+					return page, fmt.Errorf("Malformed menu_weight in %s: must be a number.", filename)
+				}
+				page.Menu.Weight = weight
+			case "weight":
+				weight, err := strconv.Atoi(sl[1])
+				if err != nil {
+					return page, fmt.Errorf("Malformed weight in %s: must be a number.", filename)
+				}
+				page.Weight = weight
 			default:
-				// Just ignore things that we don't know about
-				continue
+				// Keep things that we don't know about for templates
+				// and sorting.
+				if page.Params == nil {
+					page.Params = map[string]interface{}{}
+				}
+				page.Params[sl[0]] = sl[1]
 			}
 		}
 	}
 
 	if len(sd) == 0 {
-		log.Fatalf("Something went wrong parsing %s: Possible Malformed header. Reached EOF.", filename)
+		return page, fmt.Errorf("Something went wrong parsing %s: Possible Malformed header. Reached EOF.", filename)
 	}
 	page.RawMarkdown = strings.Join(sd, "\n")
-	page.Slug = slug.Slug(page.Title)
-	page.DestinationFile = path.Join(DefaultDestinationDir, "posts", page.Slug+".html")
+	page.Slug = PageSlug(page.Title)
+	if page.Slug == "" {
+		// Untitled pages would all be written to posts/.html.
+		page.Slug = PageSlug(filename)
+	}
+	page.DestinationFile = path.Join("posts", page.Slug+".html")
 	page.RelLink = "posts/" + page.Slug + ".html"
-	return page
+	return page, nil
 }
 
-func NewHTMLPage(filename string, rawContent string) HTMLPage {
-	return HTMLPage{RawHTML: rawContent, Filename: filename}
+// PageSlug turns a page title into the slug used for its URL.
+func PageSlug(title string) string {
+	return slug.Slug(title)
 }
 
-func NewContext() *Context {
-	return &Context{}
+func NewHTMLPage(filename string, rawContent string) HTMLPage {
+	return HTMLPage{RawHTML: rawContent, Filename: filename}
 }
 
-func NewContextFromSolarwindfile(path string) *Context {
-	content, err := ioutil.ReadFile(path)
-	if err != nil {
-		log.Fatal("There was a problem reading the Solarwindfile")
-	}
-
-	context := NewContext()
-	if err := json.Unmarshal(content, &context); err != nil {
-		log.Fatal("There was a problem parsing the Solarwindfile")
+func NewContext(config *Config) *Context {
+	return &Context{
+		SiteTitle:       config.SiteTitle,
+		SiteDescription: config.SiteDescription,
+		Site:            &SiteContext{Config: config},
 	}
-
-	if context.SiteTitle == "" {
-		context.SiteTitle = "Solarwind Site"
-	}
-
-	if context.SiteDescription == "" {
-		context.SiteDescription = "This is a static site generated with Solarwind: https://github.com/kyleterry/solarwind"
-	}
-
-	return context
 }
 
 func IsMarkdown(ext string) bool {
@@ -188,67 +259,88 @@ func IsMarkdown(ext string) bool {
 	return false
 }
 
-func ListFiles(dir string, extension string) []FileMapper {
+// ListFiles lists the files with extension in dir. Where they are written is
+// up to the language they're in.
+func ListFiles(dir string, extension string) ([]FileMapper, error) {
 	files, err := filepath.Glob(fmt.Sprintf("%s/*.%s", dir, extension))
 	if err != nil {
-		log.Fatal("There was an error globbing for files")
+		return nil, fmt.Errorf("There was an error globbing for files: %s", err)
 	}
 	fileMaps := []FileMapper{}
 	for _, f := range files {
 		fm := FileMapper{}
 		fm.Filetype = extension
 		fm.Filename = strings.Split(filepath.Base(f), ".")[0]
-		switch dir {
-		case DefaultContentDir:
-			fm.SourceFile = f
-			fm.DestinationFile = path.Join(DefaultDestinationDir, fm.Filename+".html")
-		case path.Join(DefaultContentDir, "posts"):
-			fm.SourceFile = f
-			fm.DestinationFile = path.Join(DefaultDestinationDir, "posts", fm.Filename+".html")
-		}
+		fm.SourceFile = f
 		fileMaps = append(fileMaps, fm)
 	}
-	return fileMaps
+	return fileMaps, nil
 }
 
-func MakePublicDir(dir string) {
-	if _, err := os.Stat(dir); err == nil {
+func MakePublicDir(dir string, preserve bool) error {
+	if _, err := os.Stat(dir); err == nil && !preserve {
 		err := os.RemoveAll(dir)
 		if err != nil {
-			log.Fatalf("Could not remove dir %s", dir)
+			return fmt.Errorf("Could not remove dir %s: %s", dir, err)
 		}
 	}
-	err := os.MkdirAll(path.Join(dir, "posts"), 0755)
-	if err != nil {
-		log.Fatal(err)
-	}
+	return os.MkdirAll(path.Join(dir, "posts"), 0755)
+}
+
+// GenerateHTMLFromMarkdown renders markdown the way blackfriday.MarkdownCommon
+// does. If images is set, local images are made responsive.
+func GenerateHTMLFromMarkdown(rawMarkdown string, images *ImageProcessor) string {
+	renderer := newMarkdownRenderer(images, nil)
+	return string(blackfriday.Markdown([]byte(rawMarkdown), renderer, markdownExtensions))
 }
 
-func GenerateHTMLFromMarkdown(rawMarkdown string) string {
-	return string(blackfriday.MarkdownCommon([]byte(rawMarkdown)))
+// RenderMarkdown is GenerateHTMLFromMarkdown for content files. Links like
+// [text](ref:posts/my-post.md) are resolved with refs, and the first one
+// that can't be is returned as an error.
+func RenderMarkdown(rawMarkdown string, images *ImageProcessor, refs func(string) (string, error)) (string, error) {
+	renderer := newMarkdownRenderer(images, refs)
+	rendered := blackfriday.Markdown([]byte(rawMarkdown), renderer, markdownExtensions)
+	return string(rendered), renderer.err
 }
 
 func MakeFinalPage(htmlContent string) string {
 	return ""
 }
 
-// Cowboy error handling
-func CopyAssets(source, dest string) {
+// CopyAssets copies everything under source into dest, overwriting files that
+// are already there and minifying the ones output is set up for. A missing
+// source directory is skipped.
+func CopyAssets(source, dest string, output *OutputMinifier) error {
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return nil
+	}
+
 	err := os.MkdirAll(dest, 0755)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	err = filepath.Walk(source, func(p string, info os.FileInfo, err error) error {
+	return filepath.Walk(source, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
 		if p == source {
 			return nil
 		}
 
+		new_path := strings.Replace(p, source, dest, 1)
+
 		if info.IsDir() {
-			os.Mkdir(path.Join(dest, info.Name()), info.Mode())
-			return nil
+			return os.MkdirAll(new_path, 0755)
 		}
 
-		new_path := strings.Replace(p, source, dest, 1)
+		if output.MediaType(p) != "" {
+			content, err := ioutil.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			return output.WriteFile(new_path, content, 0644)
+		}
 
 		r, err := os.Open(p)
 		if err != nil {
@@ -266,11 +358,8 @@ func CopyAssets(source, dest string) {
 			w.Close()
 			return err
 		}
-		return nil
+		return w.Close()
 	})
-	if err != nil {
-		log.Fatal(err)
-	}
 }
 
 // Sorting
@@ -291,119 +380,519 @@ func (p Posts) Swap(i, j int) {
 	p[i], p[j] = p[j], p[i]
 }
 
-// GenerateCommand code
-type GenerateCommand struct {
-	Ui cli.Ui
-}
+// Build generates the site into out, which is usually s.DestinationDir,
+// calling the hooks of the site's plugins along the way. out is emptied
+// first, so it may not be or contain the project. It stops at the first
+// error, or as soon as ctx is done.
+func (s *Site) Build(ctx context.Context, out string) error {
+	config := s.Config
+	logger := s.Logger
 
-func (c *GenerateCommand) Help() string {
-	helpText := `
-usage: solarwind generate
-	This command will build a solarwind project and put everything in ./public.
-	`
-	return helpText
-}
+	if err := s.checkOutputDir(out); err != nil {
+		return err
+	}
 
-func (c *GenerateCommand) Synopsis() string {
-	return "Builds a static site from markdown content."
-}
+	logger.Println("Making public directory")
+	if err := MakePublicDir(out, config.Build.PreserveOutput); err != nil {
+		return err
+	}
+
+	logger.Println("Loading data files")
+	data, err := LoadData(s.DataDir)
+	if err != nil {
+		return err
+	}
+	translations, err := LoadTranslations(s.I18nDir)
+	if err != nil {
+		return err
+	}
 
-func (c *GenerateCommand) Run(args []string) int {
-	if _, err := os.Stat(Solarwindfile); err != nil {
-		log.Fatal("You need to create a `Solarwindfile` in the directory you'd like to serve as your site.")
+	assets := NewAssetPipeline(config.Assets, s.StaticSearchDirs(), path.Join(out, "static"))
+	images := NewImageProcessor(config.Images, s.StaticSearchDirs(), path.Join(s.CacheDir, "images"), path.Join(out, "static"))
+	images.Logger = logger
+	funcs := template.FuncMap(assets.TemplateFuncs())
+	for name, fn := range images.TemplateFuncs() {
+		funcs[name] = fn
+	}
+	funcs["markdownify"] = func(rawMarkdown interface{}) template.HTML {
+		return template.HTML(GenerateHTMLFromMarkdown(fmt.Sprint(rawMarkdown), images))
+	}
+	output := NewOutputMinifier(config.Minify)
+
+	// Static files go first so bundles and resized images written while
+	// rendering replace the sources they were made from.
+	logger.Println("Copying static assets")
+	for _, dir := range s.StaticDirs() {
+		if err := CopyAssets(dir, path.Join(out, "static"), output); err != nil {
+			return err
+		}
 	}
 
-	log.Println("Making public directory")
-	MakePublicDir(DefaultDestinationDir)
-	context := NewContextFromSolarwindfile(DefaultSolarwindfilePath)
-	var posts Posts
 	templateCache := make(map[string][]byte)
 
-	log.Println("Caching templates")
+	logger.Println("Caching templates")
 	for _, tmpl_file := range []string{"index.html", "page.html", "post.html"} {
 		name := strings.SplitN(tmpl_file, ".", 2)[0]
-		cache, err := ioutil.ReadFile(path.Join(DefaultTemplateDir, tmpl_file))
+		cache, err := s.ReadTemplate(tmpl_file)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		templateCache[name] = cache
 	}
 
-	log.Println("Collecting content")
-	rootShortMarkdownFiles := ListFiles(DefaultContentDir, TypeMarkdown)
-	rootLongMarkdownFiles := ListFiles(DefaultContentDir, TypeMarkdownLong)
-	rootMarkdownFiles := append(rootShortMarkdownFiles, rootLongMarkdownFiles...)
-	rootHTMLFiles := ListFiles(DefaultContentDir, TypeHTML)
-	postShortMarkdownFiles := ListFiles(DefaultPostsDir, TypeMarkdown)
-	postLongMarkdownFiles := ListFiles(DefaultPostsDir, TypeMarkdownLong)
-	postMarkdownFiles := append(postShortMarkdownFiles, postLongMarkdownFiles...)
+	// refs is set once every page has been read, before any of the
+	// functions using it can be called.
+	var refs *RefResolver
+	languages := SiteLanguages(config, s.ContentDir, out)
+	builds := make([]*siteBuild, len(languages))
+	translated := make([][]*MarkdownPage, len(languages))
+	for i, language := range languages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if config.Multilingual() {
+			logger.Printf("Building %s", language.Code)
+		}
+
+		// Every language gets its own T, so each needs its own copy of
+		// the template functions.
+		languageFuncs := template.FuncMap{}
+		for name, fn := range funcs {
+			languageFuncs[name] = fn
+		}
+		code := language.Code
+		languageFuncs["T"] = func(id string, args ...interface{}) string {
+			return translations.Translate(code, config.Language, id, args...)
+		}
+
+		languageFuncs["ref"] = func(file string) (string, error) {
+			return refs.Ref(code, file)
+		}
+		languageFuncs["relref"] = func(file string) (string, error) {
+			return refs.RelRef(code, file)
+		}
+
+		builds[i], err = s.newSiteBuild(language, languages, data, languageFuncs)
+		if err != nil {
+			return err
+		}
+		translated[i] = builds[i].translatablePages()
+	}
+	LinkTranslations(translated)
+
+	outputs := OutputFiles{}
+	if config.Multilingual() {
+		outputs.Add(path.Join(out, "index.html"), "the language redirect")
+	}
+	for _, b := range builds {
+		if err := b.addOutputs(outputs); err != nil {
+			return err
+		}
+	}
+
+	var aliasPages []*MarkdownPage
+	for _, pages := range translated {
+		aliasPages = append(aliasPages, pages...)
+	}
+	aliases, err := CollectAliases(aliasPages)
+	if err != nil {
+		return err
+	}
+	for _, alias := range aliases {
+		if config.Redirects.Stubs {
+			if err := outputs.Add(AliasFile(out, alias.From), "the alias in "+alias.Source); err != nil {
+				return err
+			}
+		}
+	}
+	refs = NewRefResolver(languages, translated, config.BaseURL)
+
+	logger.Println("Rendering markdown")
+	for _, b := range builds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := b.renderMarkdown(images, refs); err != nil {
+			return err
+		}
+	}
+
+	logger.Println("Generating site")
+	for _, b := range builds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := b.render(templateCache, output); err != nil {
+			return err
+		}
+	}
+
+	logger.Println("Writing redirects")
+	if err := WriteAliases(out, aliases, config.Redirects, config.BaseURL); err != nil {
+		return err
+	}
+	s.setAliases(aliases)
+
+	if config.Multilingual() {
+		for _, language := range languages {
+			if language.Default {
+				if err := WriteRedirect(path.Join(out, "index.html"), "/"+language.URLPrefix); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := s.afterBuild(out); err != nil {
+		return err
+	}
+
+	logger.Println("Done!")
+	return nil
+}
+
+// siteBuild is everything parsed from the content of one language, ready to
+// be rendered.
+type siteBuild struct {
+	site       *Site
+	language   *Language
+	context    *Context
+	funcs      template.FuncMap
+	shortcodes *ShortcodeRenderer
+	posts      Posts
+	pages      []contentPage
+}
+
+// contentPage is a file from the root of the content directory. HTML files
+// get a stand-in MarkdownPage so menus and translations know where they live.
+type contentPage struct {
+	file FileMapper
+	page MarkdownPage
+	html *HTMLPage
+}
+
+// newSiteBuild reads the content of language. Markdown isn't rendered until
+// renderMarkdown, once every page of every language knows where it will be
+// written.
+func (s *Site) newSiteBuild(language *Language, languages []*Language, data map[string]interface{}, funcs template.FuncMap) (*siteBuild, error) {
+	config := language.Config
+	logger := s.Logger
+	b := &siteBuild{site: s, language: language, funcs: funcs}
+	b.context = NewContext(config)
+	b.context.Site.Data = data
+	b.context.Site.Languages = languages
+	b.shortcodes = NewShortcodeRenderer(b.context.Site, s.TemplateDirs(), funcs)
+
+	logger.Println("Building collection pages")
+	b.context.Site.Collections = map[string][]MarkdownPage{}
+	for name, options := range config.Collections {
+		pages, err := BuildCollectionPages(name, options, data)
+		if err != nil {
+			return nil, err
+		}
+		for i := range pages {
+			pages[i].translationKey = name + "/" + pages[i].Slug
+			language.relocate(&pages[i])
+			if err := s.pageParsed(&pages[i]); err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := config.Sections[name]; ok {
+			// Collections keep the order of their data file unless
+			// they're given one.
+			SortPages(pages, config.Section(name))
+		}
+		LinkNeighbours(pages)
+		b.context.Site.Collections[name] = pages
+	}
+
+	logger.Println("Collecting content")
+	var rootMarkdownFiles, rootHTMLFiles, postMarkdownFiles []FileMapper
+	for _, list := range []struct {
+		files     *[]FileMapper
+		dir       string
+		extension string
+	}{
+		{&rootMarkdownFiles, s.ContentDir, TypeMarkdown},
+		{&rootMarkdownFiles, s.ContentDir, TypeMarkdownLong},
+		{&rootHTMLFiles, s.ContentDir, TypeHTML},
+		{&postMarkdownFiles, s.PostsDir, TypeMarkdown},
+		{&postMarkdownFiles, s.PostsDir, TypeMarkdownLong},
+	} {
+		files, err := language.ContentFiles(list.dir, list.extension)
+		if err != nil {
+			return nil, err
+		}
+		*list.files = append(*list.files, files...)
+	}
 	fileCount := len(rootMarkdownFiles) + len(rootHTMLFiles) + len(postMarkdownFiles)
-	log.Printf("Found %d files", fileCount)
+	logger.Printf("Found %d files", fileCount)
 
 	// Merge root files so one loop is needed
 	rootFilesToRead := append(rootMarkdownFiles, rootHTMLFiles...)
 
-	log.Println("Parsing posts")
+	logger.Println("Parsing posts")
 	for _, file := range postMarkdownFiles {
 		content, err := ioutil.ReadFile(file.SourceFile)
 		if err != nil {
-			log.Fatalf("There was an error reading the file: %s", err)
+			return nil, fmt.Errorf("There was an error reading the file: %s", err)
 		}
 
-		post := NewMarkdownPage(file.Filename, string(content))
-		post.FinalHTML = template.HTML(GenerateHTMLFromMarkdown(post.RawMarkdown))
-		posts = append(posts, post)
+		logger.Printf("Parsing %s", file.Filename)
+		post, err := NewMarkdownPage(file.Filename, string(content))
+		if err != nil {
+			return nil, err
+		}
+		if post.Draft && !config.Build.Drafts {
+			continue
+		}
+		post.sourceFile = file.SourceFile
+		post.translationKey = "posts/" + file.Filename
+		b.posts = append(b.posts, post)
 	}
 
-	sort.Sort(posts)
-	context.Posts = &posts
+	if err := UniqueSlugs(b.posts, config.Build.SlugCollisions == SlugCollisionsSuffix); err != nil {
+		return nil, err
+	}
+	for i := range b.posts {
+		language.relocate(&b.posts[i])
+		if err := s.pageParsed(&b.posts[i]); err != nil {
+			return nil, err
+		}
+	}
 
-	log.Println("Parsing pages and generating site")
+	SortPages(b.posts, config.Section("posts"))
+	ComputeRelated(b.posts, config.Related)
+	LinkNeighbours(b.posts)
+	b.context.Posts = &b.posts
+
+	logger.Println("Parsing pages")
+	menuPages := append([]MarkdownPage{}, b.posts...)
 	for _, file := range rootFilesToRead {
 		content, err := ioutil.ReadFile(file.SourceFile)
 		if err != nil {
-			log.Fatalf("There was an error reading the file: %s", err)
+			return nil, fmt.Errorf("There was an error reading the file: %s", err)
 		}
-		var page Page
+		p := contentPage{file: file}
 		if IsMarkdown(file.Filetype) {
-			md := NewMarkdownPage(file.Filename, string(content))
-			md.FinalHTML = template.HTML(GenerateHTMLFromMarkdown(md.RawMarkdown))
-			page = md
+			logger.Printf("Parsing %s", file.Filename)
+			md, err := NewMarkdownPage(file.Filename, string(content))
+			if err != nil {
+				return nil, err
+			}
+			if md.Draft && !config.Build.Drafts {
+				continue
+			}
+			md.RelLink = file.Filename + ".html"
+			md.sourceFile = file.SourceFile
+			language.relocate(&md)
+			if err := s.pageParsed(&md); err != nil {
+				return nil, err
+			}
+			menuPages = append(menuPages, md)
+			p.page = md
 		} else {
 			html := NewHTMLPage(file.Filename, string(content))
 			html.FinalHTML = template.HTML(string(content))
-			page = html
+			p.html = &html
+			// HTML pages have no header, but menus still need to know
+			// where they live to work out which entry is active.
+			p.page = MarkdownPage{Filename: file.Filename, RelLink: file.Filename + ".html"}
+			language.relocate(&p.page)
 		}
+		p.page.translationKey = file.Filename
+		b.pages = append(b.pages, p)
+	}
+
+	menus, err := BuildMenus(config.Menus, menuPages, config.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	b.context.Site.Menus = menus
 
-		t := template.Must(template.New("page").Parse(string(templateCache["index"]) + string(templateCache["page"]) + string(page.GetFinalHTML())))
+	return b, nil
+}
 
-		// TODO: make custom io.Writer to write the template directly to a file
-		b := &bytes.Buffer{}
-		t.Execute(b, context)
+// renderMarkdown expands the shortcodes in every markdown page and renders
+// it, resolving ref: links with refs.
+func (b *siteBuild) renderMarkdown(images *ImageProcessor, refs *RefResolver) error {
+	code := b.language.Code
+	resolve := func(file string) (string, error) {
+		return refs.RelRef(code, file)
+	}
 
-		err = ioutil.WriteFile(file.DestinationFile, b.Bytes(), 0755)
+	render := func(page *MarkdownPage) error {
+		if err := b.site.beforeRender(page); err != nil {
+			return err
+		}
+		expanded, err := b.shortcodes.Expand(page.RawMarkdown, *page)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("There was an error expanding shortcodes in %s: %s", page.sourceFile, err)
+		}
+		rendered, err := RenderMarkdown(expanded, images, resolve)
+		if err != nil {
+			return fmt.Errorf("There was an error rendering %s: %s", page.sourceFile, err)
+		}
+		page.FinalHTML = template.HTML(rendered)
+		return b.site.afterRender(page)
+	}
+
+	for i := range b.posts {
+		if err := render(&b.posts[i]); err != nil {
+			return err
+		}
+	}
+	for i := range b.pages {
+		if b.pages[i].html == nil {
+			if err := render(&b.pages[i].page); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addOutputs records every file the build will write.
+func (b *siteBuild) addOutputs(outputs OutputFiles) error {
+	for _, post := range b.posts {
+		if err := outputs.Add(post.DestinationFile, post.sourceFile); err != nil {
+			return err
+		}
+	}
+	for _, p := range b.pages {
+		if err := outputs.Add(p.file.DestinationFile, p.file.SourceFile); err != nil {
+			return err
+		}
+	}
+	for name, pages := range b.context.Site.Collections {
+		for _, page := range pages {
+			if err := outputs.Add(page.DestinationFile, "collection "+name); err != nil {
+				return err
+			}
+		}
+	}
+	if options := b.language.Config.Search; options.Enabled {
+		if err := outputs.Add(path.Join(b.language.DestinationDir, options.Output), "the search index"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translatablePages returns pointers to every page of the build, for linking
+// them to their translations.
+func (b *siteBuild) translatablePages() []*MarkdownPage {
+	var pages []*MarkdownPage
+	for i := range b.posts {
+		pages = append(pages, &b.posts[i])
+	}
+	for i := range b.pages {
+		pages = append(pages, &b.pages[i].page)
+	}
+	for _, collection := range b.context.Site.Collections {
+		for i := range collection {
+			pages = append(pages, &collection[i])
+		}
+	}
+	return pages
+}
+
+func (b *siteBuild) render(templateCache map[string][]byte, output *OutputMinifier) error {
+	context := b.context
+	for _, p := range b.pages {
+		context.CurrentPage = p.page
+
+		body := markdownPageTemplate
+		if p.html != nil {
+			body = string(p.html.GetFinalHTML())
+		}
+		// Rendered markdown isn't a template, so hand it to page.html the
+		// same way HTML content files do.
+		source := string(templateCache["index"]) + string(templateCache["page"]) + body
+		if err := b.write(source, p.file.SourceFile, p.file.DestinationFile, output); err != nil {
+			return err
 		}
 	}
 
 	for _, post := range *context.Posts {
 		context.CurrentPage = post
-		t := template.Must(template.New("page").Parse(string(templateCache["index"]) + string(templateCache["post"])))
-
-		b := &bytes.Buffer{}
-		t.Execute(b, context)
+		source := string(templateCache["index"]) + string(templateCache["post"])
+		if err := b.write(source, post.Filename, post.DestinationFile, output); err != nil {
+			return err
+		}
+	}
 
-		err := ioutil.WriteFile(post.DestinationFile, b.Bytes(), 0755)
+	for name, pages := range context.Site.Collections {
+		options := b.language.Config.Collections[name].withDefaults(name)
+		cache, err := b.site.ReadTemplate(options.Template)
 		if err != nil {
-			panic(err)
+			return err
+		}
+
+		for _, page := range pages {
+			context.CurrentPage = page
+			source := string(templateCache["index"]) + string(cache)
+			if err := b.write(source, page.RelLink, page.DestinationFile, output); err != nil {
+				return err
+			}
+		}
+	}
+
+	if options := b.language.Config.Search; options.Enabled {
+		searchable := append([]MarkdownPage{}, b.posts...)
+		for _, p := range b.pages {
+			if p.html == nil {
+				searchable = append(searchable, p.page)
+			}
+		}
+		index := BuildSearchIndex(searchable, options)
+		if err := WriteSearchIndex(path.Join(b.language.DestinationDir, options.Output), index, output); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	log.Println("Copying static assets")
-	CopyAssets(DefaultStaticDir, path.Join(DefaultDestinationDir, "static"))
+// write parses source as a template and renders it with the build's context
+// into dest. name identifies the page in errors.
+func (b *siteBuild) write(source, name, dest string, output *OutputMinifier) error {
+	t, err := template.New("page").Funcs(b.funcs).Parse(source)
+	if err != nil {
+		return fmt.Errorf("There was an error parsing the template for %s: %s", name, err)
+	}
 
-	log.Println("Done!")
+	// TODO: make custom io.Writer to write the template directly to a file
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, b.context); err != nil {
+		return fmt.Errorf("There was an error rendering %s: %s", name, err)
+	}
 
-	return 0
+	content, err := b.site.beforeWrite(dest, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(path.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return output.WriteFile(dest, content, 0755)
+}
+
+// WriteRedirect writes an HTML page to dest that sends browsers to url.
+func WriteRedirect(dest, url string) error {
+	if err := os.MkdirAll(path.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dest, []byte(fmt.Sprintf(redirectTemplate, url, url, url)), 0644)
 }
+
+const redirectTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<link rel="canonical" href="%s">
+<meta http-equiv="refresh" content="0; url=%s">
+</head>
+</html>
+`
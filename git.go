@@ -0,0 +1,185 @@
+package solarwind
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const DefaultDeployBranch = "gh-pages"
+
+// GitTarget commits the generated site to a branch of a git repository, the
+// way GitHub Pages wants it. Only git plumbing is used, with an index of its
+// own, so neither the working tree nor the index of the repository are
+// touched.
+type GitTarget struct {
+	// Repo is the repository to commit to. It can be bare.
+	Repo   string
+	Branch string
+
+	// Remote is pushed the branch after every deploy if it's set.
+	Remote string
+
+	// CNAME is written to a CNAME file at the root of the branch, which is
+	// how GitHub Pages learns the site's custom domain.
+	CNAME string
+
+	// Source is the project directory. The commit it's at goes into the
+	// commit message.
+	Source string
+}
+
+func NewGitTarget(options DeployOptions, source string) *GitTarget {
+	t := &GitTarget{
+		Repo:   options.Repo,
+		Branch: options.Branch,
+		Remote: options.Remote,
+		CNAME:  options.CNAME,
+		Source: source,
+	}
+	if t.Repo == "" {
+		t.Repo = source
+	}
+	if t.Branch == "" {
+		t.Branch = DefaultDeployBranch
+	}
+	return t
+}
+
+// Deploy commits the files in dir to the branch, reporting what changed to
+// w. With dryRun the branch is left where it is.
+func (t *GitTarget) Deploy(w io.Writer, dir string, dryRun bool) error {
+	gitDir, err := git(t.Repo, nil, "rev-parse", "--git-dir")
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(t.Repo, gitDir)
+	}
+	gitDir, err = filepath.Abs(gitDir)
+	if err != nil {
+		return err
+	}
+	workTree, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	index, err := ioutil.TempFile("", "solarwind-index")
+	if err != nil {
+		return err
+	}
+	index.Close()
+	os.Remove(index.Name())
+	defer os.Remove(index.Name())
+
+	env := []string{"GIT_DIR=" + gitDir, "GIT_WORK_TREE=" + workTree, "GIT_INDEX_FILE=" + index.Name()}
+	if _, err := git(workTree, env, "add", "--all", "--force", "."); err != nil {
+		return err
+	}
+	if t.CNAME != "" {
+		blob, err := gitInput(workTree, env, t.CNAME+"\n", "hash-object", "-w", "--stdin")
+		if err != nil {
+			return err
+		}
+		if _, err := git(workTree, env, "update-index", "--add", "--cacheinfo", "100644,"+blob+",CNAME"); err != nil {
+			return err
+		}
+	}
+	tree, err := git(workTree, env, "write-tree")
+	if err != nil {
+		return err
+	}
+
+	ref := "refs/heads/" + t.Branch
+	parent, _ := git(workTree, env, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	var changes string
+	if parent == "" {
+		changes, err = git(workTree, env, "ls-tree", "-r", "--name-only", tree)
+		changes = prefixLines("upload ", changes)
+	} else {
+		changes, err = git(workTree, env, "diff-tree", "-r", "--name-status", "--no-renames", parent, tree)
+		changes = strings.NewReplacer("A\t", "upload ", "M\t", "upload ", "T\t", "upload ", "D\t", "delete ").Replace(changes)
+	}
+	if err != nil {
+		return err
+	}
+	if changes == "" {
+		fmt.Fprintf(w, "%s is up to date\n", t.Branch)
+		return nil
+	}
+	fmt.Fprintln(w, changes)
+	if dryRun {
+		fmt.Fprintf(w, "Dry run: %s was not changed\n", t.Branch)
+		return nil
+	}
+
+	args := []string{"commit-tree", tree, "-m", t.message()}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	commit, err := git(workTree, env, args...)
+	if err != nil {
+		return err
+	}
+	if _, err := git(workTree, env, "update-ref", "-m", "solarwind deploy", ref, commit, parent); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Committed %s to %s\n", commit[:7], t.Branch)
+
+	if t.Remote != "" {
+		if _, err := git(workTree, env, "push", t.Remote, ref+":"+ref); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Pushed %s to %s\n", t.Branch, t.Remote)
+	}
+	return nil
+}
+
+// message is the commit message of a deploy, which names the commit the
+// project was at if it's in a repository.
+func (t *GitTarget) message() string {
+	head, err := git(t.Source, nil, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil || head == "" {
+		return "Deploy site"
+	}
+	message := fmt.Sprintf("Deploy site from %s\n\nGenerated by solarwind from commit %s", head[:7], head)
+	if status, err := git(t.Source, nil, "status", "--porcelain", "--untracked-files=no"); err == nil && status != "" {
+		message += " with uncommitted changes"
+	}
+	return message + "."
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(dir string, env []string, args ...string) (string, error) {
+	return gitInput(dir, env, "", args...)
+}
+
+func gitInput(dir string, env []string, input string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = strings.NewReader(input)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func prefixLines(prefix, s string) string {
+	if s == "" {
+		return s
+	}
+	return prefix + strings.Replace(s, "\n", "\n"+prefix, -1)
+}
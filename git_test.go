@@ -0,0 +1,183 @@
+package solarwind
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupGitTest gives git an identity for commits, including the ones made by
+// GitTarget, until cleanup restores the environment and removes root.
+func setupGitTest(t *testing.T) (root string, run func(dir string, args ...string) string, cleanup func()) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity := map[string]string{
+		"GIT_AUTHOR_NAME":     "Solarwind Test",
+		"GIT_COMMITTER_NAME":  "Solarwind Test",
+		"GIT_AUTHOR_EMAIL":    "test@example.com",
+		"GIT_COMMITTER_EMAIL": "test@example.com",
+	}
+	restore := map[string]*string{}
+	for name, value := range identity {
+		if old, ok := os.LookupEnv(name); ok {
+			restore[name] = &old
+		} else {
+			restore[name] = nil
+		}
+		os.Setenv(name, value)
+	}
+	cleanup = func() {
+		for name, old := range restore {
+			if old == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *old)
+			}
+		}
+		os.RemoveAll(root)
+	}
+
+	run = func(dir string, args ...string) string {
+		out, err := git(dir, nil, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	return root, run, cleanup
+}
+
+func TestGitTarget(t *testing.T) {
+	root, run, cleanup := setupGitTest(t)
+	defer cleanup()
+
+	project := filepath.Join(root, "project")
+	remote := filepath.Join(root, "remote.git")
+	public := filepath.Join(root, "public")
+	writeTestFiles(t, project, map[string]string{"Solarwindfile": "{}"})
+	writeTestFiles(t, public, map[string]string{"index.html": "home", "posts/a.html": "a"})
+
+	run(root, "init", "-q", project)
+	run(root, "init", "-q", "--bare", remote)
+	run(project, "add", "Solarwindfile")
+	run(project, "commit", "-q", "-m", "Start the site")
+	head := run(project, "rev-parse", "HEAD")
+	branch := run(project, "symbolic-ref", "HEAD")
+
+	target := NewGitTarget(DeployOptions{Type: DeployTypeGit, Remote: remote, CNAME: "example.com"}, project)
+	if err := target.Deploy(&bytes.Buffer{}, public, false); err != nil {
+		t.Fatal(err)
+	}
+
+	files := run(remote, "ls-tree", "-r", "--name-only", "gh-pages")
+	if files != "CNAME\nindex.html\nposts/a.html" {
+		t.Errorf("expected the site and a CNAME on gh-pages, got %q", files)
+	}
+	if cname := run(remote, "show", "gh-pages:CNAME"); cname != "example.com" {
+		t.Errorf("expected CNAME to be example.com, got %q", cname)
+	}
+	if message := run(remote, "log", "-1", "--format=%B", "gh-pages"); !strings.Contains(message, head) {
+		t.Errorf("expected the commit message to name %s, got %q", head, message)
+	}
+	if status := run(project, "status", "--porcelain"); status != "" {
+		t.Errorf("expected the working tree to be left alone, got %q", status)
+	}
+	if current := run(project, "symbolic-ref", "HEAD"); current != branch {
+		t.Errorf("expected %s to still be checked out, got %s", branch, current)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := target.Deploy(buf, public, false); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); out != "gh-pages is up to date\n" {
+		t.Errorf("expected nothing to change on a second deploy, got %q", out)
+	}
+
+	deployed := run(project, "rev-parse", "gh-pages")
+	writeTestFiles(t, public, map[string]string{"index.html": "new home"})
+	os.RemoveAll(filepath.Join(public, "posts"))
+
+	buf = &bytes.Buffer{}
+	if err := target.Deploy(buf, public, true); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "upload index.html\ndelete posts/a.html\n") {
+		t.Errorf("expected the dry run to list the changes, got %q", out)
+	}
+	if run(project, "rev-parse", "gh-pages") != deployed {
+		t.Error("expected a dry run to leave the branch alone")
+	}
+
+	if err := target.Deploy(&bytes.Buffer{}, public, false); err != nil {
+		t.Fatal(err)
+	}
+	if parent := run(project, "rev-parse", "gh-pages^"); parent != deployed {
+		t.Errorf("expected the deploy to follow %s, got %s", deployed, parent)
+	}
+	if files := run(remote, "ls-tree", "-r", "--name-only", "gh-pages"); files != "CNAME\nindex.html" {
+		t.Errorf("expected posts/a.html to be removed, got %q", files)
+	}
+}
+
+func TestGitTargetBareRepo(t *testing.T) {
+	root, run, cleanup := setupGitTest(t)
+	defer cleanup()
+
+	repo := filepath.Join(root, "site.git")
+	public := filepath.Join(root, "public")
+	writeTestFiles(t, public, map[string]string{"index.html": "home"})
+	run(root, "init", "-q", "--bare", repo)
+
+	target := NewGitTarget(DeployOptions{Type: DeployTypeGit, Repo: repo, Branch: "pages"}, public)
+	if err := target.Deploy(&bytes.Buffer{}, public, false); err != nil {
+		t.Fatal(err)
+	}
+	if files := run(repo, "ls-tree", "-r", "--name-only", "pages"); files != "index.html" {
+		t.Errorf("expected index.html on pages, got %q", files)
+	}
+	if message := run(repo, "log", "-1", "--format=%s", "pages"); message != "Deploy site" {
+		t.Errorf("expected a plain commit message outside a repository, got %q", message)
+	}
+}
+
+func TestSiteDeployGitRelativeRepo(t *testing.T) {
+	root, run, cleanup := setupGitTest(t)
+	defer cleanup()
+
+	project := filepath.Join(root, "project")
+	if err := NewSite(project); err != nil {
+		t.Fatal(err)
+	}
+	site, err := Open(project, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := site.Build(context.Background(), site.DestinationDir); err != nil {
+		t.Fatal(err)
+	}
+	repo := filepath.Join(root, "site.git")
+	run(root, "init", "-q", "--bare", repo)
+
+	// Relative repositories are relative to the project, not the working
+	// directory.
+	site.Config.Deploy = map[string]DeployOptions{"pages": {Type: DeployTypeGit, Repo: "../site.git"}}
+	if err := site.Deploy(ioutil.Discard, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if files := run(repo, "ls-tree", "-r", "--name-only", DefaultDeployBranch); !strings.Contains(files, "index.html") {
+		t.Errorf("expected the site on %s, got %q", DefaultDeployBranch, files)
+	}
+}
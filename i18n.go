@@ -0,0 +1,72 @@
+package solarwind
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Translations are the string tables in the i18n directory, by language and
+// then by string id.
+type Translations map[string]map[string]string
+
+// LoadTranslations reads the string tables in dir. Each language has a data
+// file named after it, so i18n/fr.yaml holds the French strings:
+//
+//	read_more: Lire la suite
+//	nav:
+//	  home: Accueil
+//
+// Nested keys are joined with dots, making the second string nav.home. A
+// missing dir is not an error.
+func LoadTranslations(dir string) (Translations, error) {
+	data, err := LoadData(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	translations := Translations{}
+	for language, value := range data {
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("i18n %s: must be a map of string ids to strings", language)
+		}
+		table := map[string]string{}
+		flattenTranslations("", values, table)
+		translations[language] = table
+	}
+	return translations, nil
+}
+
+func flattenTranslations(prefix string, values map[string]interface{}, table map[string]string) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if nested, ok := values[key].(map[string]interface{}); ok {
+			flattenTranslations(prefix+key+".", nested, table)
+			continue
+		}
+		table[prefix+key] = fmt.Sprint(values[key])
+	}
+}
+
+// Translate returns the string id in language, falling back to the default
+// language and then to id itself. Any args are formatted into the string the
+// way fmt.Sprintf does.
+func (t Translations) Translate(language, fallback, id string, args ...interface{}) string {
+	s, ok := t[language][id]
+	if !ok {
+		s, ok = t[fallback][id]
+	}
+	if !ok {
+		s = id
+	}
+	if len(args) > 0 && strings.Contains(s, "%") {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
@@ -0,0 +1,356 @@
+package solarwind
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+	ImageFormatGIF  = "gif"
+)
+
+var imageExtensions = map[string]string{
+	ImageFormatJPEG: ".jpg",
+	ImageFormatPNG:  ".png",
+	ImageFormatGIF:  ".gif",
+}
+
+// ImageOptions controls responsive image processing.
+type ImageOptions struct {
+	// Widths are the widths, in pixels, that responsive images are resized
+	// to. Images are never scaled up.
+	Widths []int `json:"widths"`
+
+	// Quality is the JPEG quality, from 1 to 100.
+	Quality int `json:"quality"`
+
+	// Format converts every processed image to jpeg, png or gif. Images
+	// keep their own format if it's empty.
+	Format string `json:"format"`
+
+	// Sizes is the sizes attribute given to responsive markdown images.
+	Sizes string `json:"sizes"`
+
+	// Background is the colour, like #ffffff, that transparent images are
+	// drawn over when they're converted to JPEG. It defaults to white.
+	Background string `json:"background"`
+
+	// Markdown turns local markdown images into responsive images when
+	// Widths is set.
+	Markdown bool `json:"markdown"`
+}
+
+var DefaultImageOptions = ImageOptions{
+	Quality:  85,
+	Sizes:    "100vw",
+	Markdown: true,
+}
+
+// ProcessedImage is an image written by the ImageProcessor.
+type ProcessedImage struct {
+	URL    string
+	Width  int
+	Height int
+}
+
+// ResponsiveImage is a source image resized to each of the configured widths.
+type ResponsiveImage struct {
+	// Src is the largest variant.
+	Src      *ProcessedImage
+	Variants []*ProcessedImage
+}
+
+func (r *ResponsiveImage) URL() string {
+	return r.Src.URL
+}
+
+// Srcset renders the variants as the value of a srcset attribute.
+func (r *ResponsiveImage) Srcset() string {
+	sources := make([]string, len(r.Variants))
+	for i, v := range r.Variants {
+		sources[i] = fmt.Sprintf("%s %dw", v.URL, v.Width)
+	}
+	return strings.Join(sources, ", ")
+}
+
+// ImageProcessor resizes and converts images from the static directories.
+// Processed images are kept in CacheDir, keyed by a hash of the source image
+// and the processing options, so unchanged images are only processed once.
+type ImageProcessor struct {
+	Options ImageOptions
+
+	// SourceDirs are searched in order for source images.
+	SourceDirs []string
+
+	CacheDir string
+
+	// OutputDir is where processed images are written. They are served
+	// from /static/.
+	OutputDir string
+
+	// Logger is told about markdown images that couldn't be made
+	// responsive. NewImageProcessor makes one that discards everything.
+	Logger *log.Logger
+
+	processed map[string]*ProcessedImage
+}
+
+func NewImageProcessor(options ImageOptions, sourceDirs []string, cacheDir, outputDir string) *ImageProcessor {
+	return &ImageProcessor{
+		Options:    options,
+		SourceDirs: sourceDirs,
+		CacheDir:   cacheDir,
+		OutputDir:  outputDir,
+		Logger:     log.New(ioutil.Discard, "", 0),
+		processed:  map[string]*ProcessedImage{},
+	}
+}
+
+// TemplateFuncs returns the template functions backed by the processor:
+//
+//	{{ with responsive "images/photo.jpg" }}
+//	  <img src="{{ .URL }}" srcset="{{ .Srcset }}" sizes="100vw">
+//	{{ end }}
+//	<img src="{{ (resize "images/photo.jpg" 640).URL }}">
+//	<img src="{{ (convert "images/diagram.png" "jpeg").URL }}">
+func (p *ImageProcessor) TemplateFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"responsive": p.Responsive,
+		"resize": func(file string, width int) (*ProcessedImage, error) {
+			return p.Process(file, width, "")
+		},
+		"convert": func(file, format string) (*ProcessedImage, error) {
+			return p.Process(file, 0, format)
+		},
+	}
+}
+
+// Responsive resizes file to each of the configured widths that is smaller
+// than the image itself. The image at its own size is always included. The
+// source is read and decoded once for all of the variants.
+func (p *ImageProcessor) Responsive(file string) (*ResponsiveImage, error) {
+	if len(p.Options.Widths) == 0 {
+		return nil, fmt.Errorf("responsive %s: no image widths are configured", file)
+	}
+
+	src := &sourceImage{file: file}
+	original, err := p.process(src, 0, "")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ResponsiveImage{}
+	for _, width := range p.Options.Widths {
+		if width >= original.Width {
+			continue
+		}
+		v, err := p.process(src, width, "")
+		if err != nil {
+			return nil, err
+		}
+		r.Variants = append(r.Variants, v)
+	}
+	r.Variants = append(r.Variants, original)
+	r.Src = original
+	return r, nil
+}
+
+// Find returns the path of file in the first source directory that has it.
+func (p *ImageProcessor) Find(file string) (string, bool) {
+	for _, dir := range p.SourceDirs {
+		source := path.Join(dir, file)
+		if info, err := os.Stat(source); err == nil && !info.IsDir() {
+			return source, true
+		}
+	}
+	return "", false
+}
+
+// Process writes file resized to width and converted to format. A width of
+// zero, or one wider than the image, keeps the image's own width, and an
+// empty format falls back to the configured format and then to the image's
+// own.
+func (p *ImageProcessor) Process(file string, width int, format string) (*ProcessedImage, error) {
+	return p.process(&sourceImage{file: file}, width, format)
+}
+
+// sourceImage is a source image that is read and decoded on first use, so
+// it can be shared between the variants of a responsive image.
+type sourceImage struct {
+	file    string
+	content []byte
+	decoded image.Image
+}
+
+func (p *ImageProcessor) read(src *sourceImage) ([]byte, error) {
+	if src.content != nil {
+		return src.content, nil
+	}
+	source, ok := p.Find(src.file)
+	if !ok {
+		return nil, fmt.Errorf("image %s was not found in %s", src.file, strings.Join(p.SourceDirs, " or "))
+	}
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+	src.content = content
+	return content, nil
+}
+
+func (src *sourceImage) decode() (image.Image, error) {
+	if src.decoded == nil {
+		img, _, err := image.Decode(bytes.NewReader(src.content))
+		if err != nil {
+			return nil, err
+		}
+		src.decoded = img
+	}
+	return src.decoded, nil
+}
+
+func (p *ImageProcessor) process(src *sourceImage, width int, format string) (*ProcessedImage, error) {
+	file := src.file
+	memo := fmt.Sprintf("%s\x00%d\x00%s", file, width, format)
+	if img, ok := p.processed[memo]; ok {
+		return img, nil
+	}
+
+	content, err := p.read(src)
+	if err != nil {
+		return nil, err
+	}
+
+	config, sourceFormat, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("could not read image %s: %s", file, err)
+	}
+	if config.Width == 0 || config.Height == 0 {
+		return nil, fmt.Errorf("could not read image %s: it is %dx%d", file, config.Width, config.Height)
+	}
+
+	if width <= 0 || width > config.Width {
+		width = config.Width
+	}
+	height := (config.Height*width + config.Width/2) / config.Width
+	if format == "" {
+		format = p.Options.Format
+	}
+	if format == "" {
+		format = sourceFormat
+	}
+	ext, ok := imageExtensions[format]
+	if !ok {
+		return nil, fmt.Errorf("can't convert %s to %q, only jpeg, png and gif are supported", file, format)
+	}
+	background, err := parseColor(p.Options.Background)
+	if err != nil {
+		return nil, fmt.Errorf("images.background: %s", err)
+	}
+
+	sum := sha256.New()
+	sum.Write(content)
+	fmt.Fprintf(sum, "\x00%d\x00%s\x00%d", width, format, p.Options.Quality)
+	if format == ImageFormatJPEG {
+		fmt.Fprintf(sum, "\x00%v", background)
+	}
+	key := hex.EncodeToString(sum.Sum(nil))[:16]
+
+	cached := path.Join(p.CacheDir, key+ext)
+	if _, err := os.Stat(cached); err != nil {
+		var processed []byte
+		if width == config.Width && format == sourceFormat {
+			processed = content
+		} else {
+			processed, err = p.transform(src, width, height, format, background)
+			if err != nil {
+				return nil, fmt.Errorf("could not process image %s: %s", file, err)
+			}
+		}
+		if err := os.MkdirAll(p.CacheDir, 0755); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(cached, processed, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	stem := strings.TrimSuffix(file, path.Ext(file))
+	name := fmt.Sprintf("%s_%dw_%s%s", stem, width, key[:8], ext)
+	processed, err := ioutil.ReadFile(cached)
+	if err != nil {
+		return nil, err
+	}
+	dest := path.Join(p.OutputDir, name)
+	if err := os.MkdirAll(path.Dir(dest), 0755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(dest, processed, 0644); err != nil {
+		return nil, err
+	}
+
+	img := &ProcessedImage{URL: "/static/" + name, Width: width, Height: height}
+	p.processed[memo] = img
+	return img, nil
+}
+
+// transform scales src to width by height and encodes it as format. JPEG
+// has no transparency, so JPEGs are drawn over the background colour.
+func (p *ImageProcessor) transform(src *sourceImage, width, height int, format string, background color.Color) ([]byte, error) {
+	img, err := src.decode()
+	if err != nil {
+		return nil, err
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	op := draw.Src
+	if format == ImageFormatJPEG {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(background), image.ZP, draw.Src)
+		op = draw.Over
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), op, nil)
+
+	b := &bytes.Buffer{}
+	switch format {
+	case ImageFormatJPEG:
+		err = jpeg.Encode(b, dst, &jpeg.Options{Quality: p.Options.Quality})
+	case ImageFormatPNG:
+		err = png.Encode(b, dst)
+	case ImageFormatGIF:
+		err = gif.Encode(b, dst, nil)
+	}
+	return b.Bytes(), err
+}
+
+// parseColor parses a hex colour like #fff or #ffffff. An empty colour is
+// white.
+func parseColor(s string) (color.Color, error) {
+	if s == "" {
+		return color.White, nil
+	}
+	digits := strings.TrimPrefix(s, "#")
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	var r, g, b uint8
+	if n, err := fmt.Sscanf(digits, "%02x%02x%02x", &r, &g, &b); len(digits) != 6 || n != 3 || err != nil {
+		return nil, fmt.Errorf("%q is not a colour like #ffffff", s)
+	}
+	return color.RGBA{r, g, b, 255}, nil
+}
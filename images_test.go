@@ -0,0 +1,200 @@
+package solarwind
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestImageProcessor(t *testing.T) (*ImageProcessor, string) {
+	dir, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	static := filepath.Join(dir, "static")
+	if err := os.MkdirAll(filepath.Join(static, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for x := 0; x < 100; x++ {
+		for y := 0; y < 50; y++ {
+			src.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	f, err := os.Create(filepath.Join(static, "images", "photo.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, src); err != nil {
+		t.Fatal(err)
+	}
+
+	options := DefaultImageOptions
+	options.Widths = []int{40, 200}
+	return NewImageProcessor(options, []string{static}, filepath.Join(dir, "cache"), filepath.Join(dir, "public", "static")), dir
+}
+
+func TestResponsiveImagesAreResizedAndCached(t *testing.T) {
+	p, dir := newTestImageProcessor(t)
+	defer os.RemoveAll(dir)
+
+	img, err := p.Responsive("images/photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(img.Variants) != 2 || img.Variants[0].Width != 40 || img.Variants[0].Height != 20 || img.Src.Width != 100 {
+		t.Fatalf("expected a 40w variant and the 100w original, got %+v", img.Variants)
+	}
+	if !strings.HasPrefix(img.Srcset(), "/static/images/photo_40w_") || !strings.HasSuffix(img.Srcset(), ".png 100w") {
+		t.Errorf("unexpected srcset %s", img.Srcset())
+	}
+
+	f, err := os.Open(filepath.Join(dir, "public", filepath.FromSlash(img.Variants[0].URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	config, err := png.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 40 || config.Height != 20 {
+		t.Errorf("expected a 40x20 image, got %dx%d", config.Width, config.Height)
+	}
+
+	cached, err := ioutil.ReadDir(p.CacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 2 {
+		t.Errorf("expected 2 cached images, got %d", len(cached))
+	}
+}
+
+func TestConvertChangesFormat(t *testing.T) {
+	p, dir := newTestImageProcessor(t)
+	defer os.RemoveAll(dir)
+
+	img, err := p.Process("images/photo.png", 0, ImageFormatJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(img.URL, ".jpg") {
+		t.Errorf("expected a jpeg URL, got %s", img.URL)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "public", filepath.FromSlash(img.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := jpeg.DecodeConfig(f); err != nil {
+		t.Errorf("expected a jpeg, got %s", err)
+	}
+}
+
+func TestConvertToJPEGFillsTransparency(t *testing.T) {
+	p, dir := newTestImageProcessor(t)
+	defer os.RemoveAll(dir)
+
+	f, err := os.Create(filepath.Join(dir, "static", "images", "clear.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 10, 10))); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		background string
+		expected   color.RGBA
+	}{
+		{"", color.RGBA{255, 255, 255, 255}},
+		{"#f00", color.RGBA{255, 0, 0, 255}},
+	}
+	for _, c := range cases {
+		options := p.Options
+		options.Background = c.background
+		p := NewImageProcessor(options, p.SourceDirs, p.CacheDir, p.OutputDir)
+		img, err := p.Process("images/clear.png", 0, ImageFormatJPEG)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Open(filepath.Join(dir, "public", filepath.FromSlash(img.URL)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		converted, err := jpeg.Decode(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := color.RGBAModel.Convert(converted.At(5, 5)).(color.RGBA)
+		for _, d := range []int{int(got.R) - int(c.expected.R), int(got.G) - int(c.expected.G), int(got.B) - int(c.expected.B)} {
+			if d < -8 || d > 8 {
+				t.Errorf("expected transparency to become %v, got %v", c.expected, got)
+				break
+			}
+		}
+	}
+}
+
+func TestProcessRejectsEmptyImages(t *testing.T) {
+	p, dir := newTestImageProcessor(t)
+	defer os.RemoveAll(dir)
+
+	// A GIF with a 0x0 logical screen and no frames.
+	empty := []byte("GIF89a\x00\x00\x00\x00\x00\x00\x00;")
+	if err := ioutil.WriteFile(filepath.Join(dir, "static", "images", "empty.gif"), empty, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := p.Process("images/empty.gif", 0, "")
+	if err == nil || !strings.Contains(err.Error(), "0x0") {
+		t.Errorf("expected an error for an empty image, got %v", err)
+	}
+}
+
+func TestMarkdownImagesAreResponsive(t *testing.T) {
+	p, dir := newTestImageProcessor(t)
+	defer os.RemoveAll(dir)
+
+	rendered := GenerateHTMLFromMarkdown("![A photo](/static/images/photo.png)\n\n![Remote](https://example.com/a.png)\n", p)
+	if !strings.Contains(rendered, `srcset="/static/images/photo_40w_`) || !strings.Contains(rendered, `alt="A photo"`) {
+		t.Errorf("expected a responsive image, got %s", rendered)
+	}
+	if !strings.Contains(rendered, `<img src="https://example.com/a.png" alt="Remote" />`) {
+		t.Errorf("expected remote images to be left alone, got %s", rendered)
+	}
+}
+
+func TestMarkdownImageErrorsGoToTheLogger(t *testing.T) {
+	p, dir := newTestImageProcessor(t)
+	defer os.RemoveAll(dir)
+	logged := &bytes.Buffer{}
+	p.Logger = log.New(logged, "", 0)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "static", "images", "broken.png"), []byte("not a png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rendered := GenerateHTMLFromMarkdown("![Broken](/static/images/broken.png)\n", p)
+	if !strings.Contains(rendered, `<img src="/static/images/broken.png" alt="Broken" />`) {
+		t.Errorf("expected the image to be rendered as usual, got %s", rendered)
+	}
+	if !strings.Contains(logged.String(), "Could not make /static/images/broken.png responsive") {
+		t.Errorf("expected the error to be logged, got %q", logged)
+	}
+}
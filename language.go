@@ -0,0 +1,206 @@
+package solarwind
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LanguageOptions overrides parts of the Solarwindfile for one language of a
+// multilingual site.
+type LanguageOptions struct {
+	// Name is what the language is called in language switchers, like
+	// Français.
+	Name            string                 `json:"name"`
+	SiteTitle       string                 `json:"site_title"`
+	SiteDescription string                 `json:"site_description"`
+	Weight          int                    `json:"weight"`
+	Params          map[string]interface{} `json:"params"`
+	Menus           map[string][]MenuEntry `json:"menus"`
+}
+
+// Language is one of the languages a site is built in. Sites without
+// `languages` in their Solarwindfile have a single language that is built
+// straight into the output directory.
+type Language struct {
+	Code string
+	Name string
+
+	// Config is the Solarwindfile with the language's overrides applied.
+	Config *Config
+
+	// ContentDir holds content that is only in this language. It is empty
+	// for single language sites.
+	ContentDir string
+
+	DestinationDir string
+
+	// URLPrefix goes in front of the RelLink of every page in the language,
+	// like "fr/".
+	URLPrefix string
+
+	// Default is true for the language files without a language in their
+	// name belong to.
+	Default bool
+
+	// siteContentDir is the content directory of the whole site.
+	siteContentDir string
+}
+
+// Multilingual is true if the site is built in more than one language, or
+// in one language under its own directory.
+func (c *Config) Multilingual() bool {
+	return len(c.Languages) > 0
+}
+
+// SiteLanguages returns the languages a site with the given content and
+// output directories is built in, ordered by weight and then by code.
+func SiteLanguages(config *Config, contentDir, destinationDir string) []*Language {
+	if !config.Multilingual() {
+		return []*Language{{
+			Code:           config.Language,
+			Name:           config.Language,
+			Config:         config,
+			DestinationDir: destinationDir,
+			Default:        true,
+			siteContentDir: contentDir,
+		}}
+	}
+
+	var languages []*Language
+	for code, options := range config.Languages {
+		c := *config
+		c.Language = code
+		if options.SiteTitle != "" {
+			c.SiteTitle = options.SiteTitle
+		}
+		if options.SiteDescription != "" {
+			c.SiteDescription = options.SiteDescription
+		}
+		c.Params = copyConfigValues(config.Params)
+		mergeConfigValues(c.Params, copyConfigValues(options.Params))
+		if options.Menus != nil {
+			c.Menus = options.Menus
+		}
+
+		name := options.Name
+		if name == "" {
+			name = code
+		}
+		languages = append(languages, &Language{
+			Code:           code,
+			Name:           name,
+			Config:         &c,
+			ContentDir:     path.Join(contentDir, code),
+			DestinationDir: path.Join(destinationDir, code),
+			URLPrefix:      code + "/",
+			Default:        code == config.Language,
+			siteContentDir: contentDir,
+		})
+	}
+
+	sort.Slice(languages, func(i, j int) bool {
+		wi, wj := config.Languages[languages[i].Code].Weight, config.Languages[languages[j].Code].Weight
+		if wi != wj {
+			return wi < wj
+		}
+		return languages[i].Code < languages[j].Code
+	})
+	return languages
+}
+
+// ContentFiles lists the files with extension in dir, which is the content
+// directory of the site or its posts directory, that are in this language. Those are the files named like
+// post.<code>.md, files without a language in their name if this is the
+// default language, and everything in the same place under the language's
+// own content directory.
+func (l *Language) ContentFiles(dir, extension string) ([]FileMapper, error) {
+	rel, err := filepath.Rel(l.siteContentDir, dir)
+	if err != nil {
+		return nil, err
+	}
+	listed, err := ListFiles(dir, extension)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FileMapper
+	for _, file := range listed {
+		code := fileLanguage(file.SourceFile)
+		if _, ok := l.Config.Languages[code]; !ok {
+			// Dots that aren't followed by a language are just part of
+			// the name.
+			code = ""
+		}
+		if code == l.Code || (code == "" && l.Default) || l.ContentDir == "" {
+			files = append(files, file)
+		}
+	}
+	if l.ContentDir != "" {
+		own, err := filepath.Glob(fmt.Sprintf("%s/*.%s", path.Join(l.ContentDir, rel), extension))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range own {
+			files = append(files, FileMapper{
+				SourceFile: f,
+				Filename:   strings.Split(filepath.Base(f), ".")[0],
+				Filetype:   extension,
+			})
+		}
+	}
+
+	seen := map[string]string{}
+	for i := range files {
+		file := &files[i]
+		if other, ok := seen[file.Filename]; ok {
+			return nil, fmt.Errorf("%s and %s are the same page in %s", other, file.SourceFile, l.Code)
+		}
+		seen[file.Filename] = file.SourceFile
+		file.DestinationFile = path.Join(l.DestinationDir, rel, file.Filename+".html")
+	}
+	return files, nil
+}
+
+// relocate moves a page that was laid out for the root of the output
+// directory into the language's directory.
+func (l *Language) relocate(page *MarkdownPage) {
+	page.Language = l.Code
+	page.DestinationFile = path.Join(l.DestinationDir, page.RelLink)
+	page.RelLink = l.URLPrefix + page.RelLink
+}
+
+// fileLanguage returns the language in a filename like post.fr.md, or an
+// empty string if it doesn't have one.
+func fileLanguage(filename string) string {
+	parts := strings.Split(filepath.Base(filename), ".")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+// LinkTranslations points every page at the same page in the other
+// languages. Pages are the same if they come from the same file, ignoring
+// language, or the same entry of a collection.
+func LinkTranslations(pages [][]*MarkdownPage) {
+	byKey := map[string][]*MarkdownPage{}
+	for _, language := range pages {
+		for _, page := range language {
+			byKey[page.translationKey] = append(byKey[page.translationKey], page)
+		}
+	}
+
+	for _, language := range pages {
+		for _, page := range language {
+			page.Translations = nil
+			for _, other := range byKey[page.translationKey] {
+				if other != page {
+					page.Translations = append(page.Translations, other)
+				}
+			}
+		}
+	}
+}
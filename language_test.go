@@ -0,0 +1,157 @@
+package solarwind
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSiteLanguages(t *testing.T) {
+	config := NewConfig()
+	config.Params = map[string]interface{}{"color": "blue", "logo": "logo.png"}
+
+	languages := SiteLanguages(config, "/site/content", "/site/public")
+	if len(languages) != 1 || languages[0].DestinationDir != "/site/public" || languages[0].URLPrefix != "" || !languages[0].Default {
+		t.Fatalf("unexpected single language %+v", languages[0])
+	}
+
+	config.Languages = map[string]LanguageOptions{
+		"fr": {Name: "Français", SiteTitle: "Mon site", Weight: 2, Params: map[string]interface{}{"color": "bleu"}},
+		"en": {Weight: 1},
+		"de": {Weight: 2},
+	}
+	languages = SiteLanguages(config, "/site/content", "/site/public")
+	if len(languages) != 3 || languages[0].Code != "en" || languages[1].Code != "de" || languages[2].Code != "fr" {
+		t.Fatalf("languages are in the wrong order: %v %v %v", languages[0].Code, languages[1].Code, languages[2].Code)
+	}
+
+	fr := languages[2]
+	if fr.Name != "Français" || fr.Default || fr.DestinationDir != "/site/public/fr" ||
+		fr.ContentDir != "/site/content/fr" || fr.URLPrefix != "fr/" {
+		t.Errorf("unexpected language %+v", fr)
+	}
+	if fr.Config.SiteTitle != "Mon site" || fr.Config.Language != "fr" ||
+		fr.Config.Params["color"] != "bleu" || fr.Config.Params["logo"] != "logo.png" {
+		t.Errorf("unexpected config %+v", fr.Config)
+	}
+	if config.Params["color"] != "blue" || config.SiteTitle != DefaultSiteTitle {
+		t.Error("language overrides leaked into the site config")
+	}
+	if !languages[0].Default {
+		t.Error("expected en to be the default language")
+	}
+}
+
+func TestLanguageContentFiles(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTestFiles(t, root, map[string]string{
+		"content/posts/hello.md":    "",
+		"content/posts/hello.fr.md": "",
+		"content/posts/v1.2.md":     "",
+		"content/fr/posts/only.md":  "",
+		"content/fr/about.md":       "",
+	})
+	contentDir := filepath.Join(root, "content")
+	postsDir := filepath.Join(contentDir, "posts")
+
+	config := NewConfig()
+	config.Languages = map[string]LanguageOptions{"en": {}, "fr": {}}
+	languages := SiteLanguages(config, contentDir, filepath.Join(root, "public"))
+
+	names := func(files []FileMapper) map[string]string {
+		m := map[string]string{}
+		for _, file := range files {
+			rel, _ := filepath.Rel(root, file.SourceFile)
+			m[file.Filename] = rel
+		}
+		return m
+	}
+
+	en, err := languages[0].ContentFiles(postsDir, TypeMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(en); len(got) != 2 || got["hello"] != "content/posts/hello.md" || got["v1"] != "content/posts/v1.2.md" {
+		t.Errorf("unexpected en posts %v", got)
+	}
+
+	fr, err := languages[1].ContentFiles(postsDir, TypeMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(fr); len(got) != 2 || got["hello"] != "content/posts/hello.fr.md" || got["only"] != "content/fr/posts/only.md" {
+		t.Errorf("unexpected fr posts %v", got)
+	}
+	if fr[0].DestinationFile != filepath.Join(root, "public/fr/posts", fr[0].Filename+".html") {
+		t.Errorf("unexpected destination %s", fr[0].DestinationFile)
+	}
+
+	pages, err := languages[1].ContentFiles(contentDir, TypeMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || pages[0].DestinationFile != filepath.Join(root, "public/fr/about.html") {
+		t.Errorf("unexpected fr pages %+v", pages)
+	}
+
+	writeTestFiles(t, root, map[string]string{"content/fr/posts/hello.md": ""})
+	if _, err := languages[1].ContentFiles(postsDir, TypeMarkdown); err == nil {
+		t.Error("expected two French hello posts to be an error")
+	}
+}
+
+func TestLinkTranslations(t *testing.T) {
+	en := []MarkdownPage{{Title: "Hello", translationKey: "posts/hello"}, {Title: "About", translationKey: "about"}}
+	fr := []MarkdownPage{{Title: "Bonjour", translationKey: "posts/hello"}}
+	LinkTranslations([][]*MarkdownPage{{&en[0], &en[1]}, {&fr[0]}})
+
+	if len(en[0].Translations) != 1 || en[0].Translations[0] != &fr[0] {
+		t.Errorf("expected Hello to be translated to Bonjour")
+	}
+	if len(fr[0].Translations) != 1 || fr[0].Translations[0] != &en[0] {
+		t.Errorf("expected Bonjour to be translated to Hello")
+	}
+	if len(en[1].Translations) != 0 {
+		t.Errorf("expected About to have no translations")
+	}
+}
+
+func TestTranslations(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTestFiles(t, root, map[string]string{
+		"en.yaml": "read_more: Read more\nposts: \"%d posts\"\nnav:\n  home: Home\n",
+		"fr.json": `{"read_more": "Lire la suite", "nav": {"home": "Accueil"}}`,
+	})
+	translations, err := LoadTranslations(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		language, id string
+		args         []interface{}
+		expected     string
+	}{
+		{"fr", "read_more", nil, "Lire la suite"},
+		{"fr", "nav.home", nil, "Accueil"},
+		{"fr", "posts", []interface{}{3}, "3 posts"},
+		{"en", "missing", nil, "missing"},
+		{"de", "nav.home", nil, "Home"},
+	}
+	for _, test := range tests {
+		if got := translations.Translate(test.language, "en", test.id, test.args...); got != test.expected {
+			t.Errorf("%s %s: expected %q, got %q", test.language, test.id, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package solarwind
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/russross/blackfriday"
+)
+
+// These match blackfriday.MarkdownCommon.
+const (
+	markdownHTMLFlags = blackfriday.HTML_USE_XHTML |
+		blackfriday.HTML_USE_SMARTYPANTS |
+		blackfriday.HTML_SMARTYPANTS_FRACTIONS |
+		blackfriday.HTML_SMARTYPANTS_DASHES |
+		blackfriday.HTML_SMARTYPANTS_LATEX_DASHES
+
+	markdownExtensions = blackfriday.EXTENSION_NO_INTRA_EMPHASIS |
+		blackfriday.EXTENSION_TABLES |
+		blackfriday.EXTENSION_FENCED_CODE |
+		blackfriday.EXTENSION_AUTOLINK |
+		blackfriday.EXTENSION_STRIKETHROUGH |
+		blackfriday.EXTENSION_SPACE_HEADERS |
+		blackfriday.EXTENSION_HEADER_IDS |
+		blackfriday.EXTENSION_BACKSLASH_LINE_BREAK |
+		blackfriday.EXTENSION_DEFINITION_LISTS
+)
+
+// markdownRenderer is blackfriday's HTML renderer with solarwind's hooks.
+type markdownRenderer struct {
+	blackfriday.Renderer
+	images *ImageProcessor
+	refs   func(string) (string, error)
+
+	// err is the first ref: link that couldn't be resolved.
+	err error
+}
+
+func newMarkdownRenderer(images *ImageProcessor, refs func(string) (string, error)) *markdownRenderer {
+	return &markdownRenderer{
+		Renderer: blackfriday.HtmlRenderer(markdownHTMLFlags, "", ""),
+		images:   images,
+		refs:     refs,
+	}
+}
+
+// Link resolves links to ref:<content file> into links to the page built
+// from the file. Anything else is rendered as usual.
+func (r *markdownRenderer) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	if r.refs != nil && bytes.HasPrefix(link, []byte(RefPrefix)) {
+		resolved, err := r.refs(string(link[len(RefPrefix):]))
+		if err != nil {
+			if r.err == nil {
+				r.err = err
+			}
+		} else {
+			link = []byte(resolved)
+		}
+	}
+	r.Renderer.Link(out, link, title, content)
+}
+
+// Image renders images from the static directory as responsive images with a
+// srcset. Anything else is rendered as usual.
+func (r *markdownRenderer) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	if r.images == nil || !r.images.Options.Markdown || len(r.images.Options.Widths) == 0 {
+		r.Renderer.Image(out, link, title, alt)
+		return
+	}
+
+	file := strings.TrimPrefix(strings.TrimPrefix(string(link), "/"), "static/")
+	if file == strings.TrimPrefix(string(link), "/") {
+		r.Renderer.Image(out, link, title, alt)
+		return
+	}
+	if _, ok := r.images.Find(file); !ok {
+		r.Renderer.Image(out, link, title, alt)
+		return
+	}
+
+	img, err := r.images.Responsive(file)
+	if err != nil {
+		r.images.Logger.Printf("Could not make %s responsive: %s", link, err)
+		r.Renderer.Image(out, link, title, alt)
+		return
+	}
+
+	fmt.Fprintf(out, `<img src="%s" srcset="%s" sizes="%s" width="%d" height="%d" alt="%s"`,
+		html.EscapeString(img.URL()), html.EscapeString(img.Srcset()), html.EscapeString(r.images.Options.Sizes),
+		img.Src.Width, img.Src.Height, html.EscapeString(string(alt)))
+	if len(title) > 0 {
+		fmt.Fprintf(out, ` title="%s"`, html.EscapeString(string(title)))
+	}
+	out.WriteString(" />")
+}
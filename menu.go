@@ -0,0 +1,171 @@
+package solarwind
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MenuEntry is a single link in one of the site menus. Entries come from the
+// `menus` key of the Solarwindfile or from the `menu` header of a page, and
+// can be nested by pointing Parent at another entry's Identifier.
+type MenuEntry struct {
+	Identifier string `json:"identifier,omitempty"`
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Weight     int    `json:"weight"`
+	Parent     string `json:"parent,omitempty"`
+	Children   Menu   `json:"-"`
+
+	// baseURL is stripped from URLs when working out the active entry.
+	baseURL string
+}
+
+// PageMenu is the `menu` header of a page:
+//
+// menu: main, footer
+// menu_name: About us
+// menu_weight: 10
+// menu_parent: company
+// menu_identifier: about
+type PageMenu struct {
+	Menus      []string
+	Name       string
+	Identifier string
+	Parent     string
+	Weight     int
+}
+
+type Menu []*MenuEntry
+
+// Menus maps a menu name to its top level entries. Templates reach it with
+// `.Site.Menus.main`.
+type Menus map[string]Menu
+
+func (m Menu) Len() int {
+	return len(m)
+}
+
+func (m Menu) Less(i, j int) bool {
+	if m[i].Weight != m[j].Weight {
+		return m[i].Weight < m[j].Weight
+	}
+	return m[i].Name < m[j].Name
+}
+
+func (m Menu) Swap(i, j int) {
+	m[i], m[j] = m[j], m[i]
+}
+
+// HasChildren is true if other entries name this one as their parent.
+func (e *MenuEntry) HasChildren() bool {
+	return len(e.Children) > 0
+}
+
+// IsActive is true if the entry links to page.
+func (e *MenuEntry) IsActive(page MarkdownPage) bool {
+	if page.RelLink == "" {
+		return false
+	}
+	return normalizeMenuURL(e.URL, e.baseURL) == normalizeMenuURL(page.RelLink, e.baseURL)
+}
+
+// HasActiveChild is true if any entry nested under this one links to page.
+func (e *MenuEntry) HasActiveChild(page MarkdownPage) bool {
+	for _, child := range e.Children {
+		if child.IsActive(page) || child.HasActiveChild(page) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeMenuURL(u, baseURL string) string {
+	if baseURL != "" {
+		u = strings.TrimPrefix(u, baseURL)
+	}
+	return strings.TrimPrefix(u, "/")
+}
+
+// BuildMenus merges the menus from the Solarwindfile with the pages that put
+// themselves in a menu, then nests and sorts every menu by weight. Entry URLs
+// starting with baseURL link to the site itself.
+func BuildMenus(configured map[string][]MenuEntry, pages []MarkdownPage, baseURL string) (Menus, error) {
+	entries := map[string][]*MenuEntry{}
+	for name, menu := range configured {
+		for _, entry := range menu {
+			e := entry
+			e.baseURL = baseURL
+			entries[name] = append(entries[name], &e)
+		}
+	}
+
+	for _, page := range pages {
+		for _, name := range page.Menu.Menus {
+			e := &MenuEntry{
+				Identifier: page.Menu.Identifier,
+				Name:       page.Menu.Name,
+				URL:        "/" + page.RelLink,
+				Weight:     page.Menu.Weight,
+				Parent:     page.Menu.Parent,
+				baseURL:    baseURL,
+			}
+			if e.Name == "" {
+				e.Name = page.Title
+			}
+			entries[name] = append(entries[name], e)
+		}
+	}
+
+	menus := Menus{}
+	for name, list := range entries {
+		menu, err := nestMenu(name, list)
+		if err != nil {
+			return nil, err
+		}
+		menus[name] = menu
+	}
+	return menus, nil
+}
+
+func nestMenu(name string, entries []*MenuEntry) (Menu, error) {
+	byID := map[string]*MenuEntry{}
+	for _, e := range entries {
+		if e.Identifier == "" {
+			e.Identifier = e.Name
+		}
+		if _, ok := byID[e.Identifier]; ok {
+			return nil, fmt.Errorf("menu %s: more than one entry has the identifier %q", name, e.Identifier)
+		}
+		byID[e.Identifier] = e
+	}
+
+	var root Menu
+	for _, e := range entries {
+		if e.Parent == "" {
+			root = append(root, e)
+			continue
+		}
+		parent, ok := byID[e.Parent]
+		if !ok {
+			return nil, fmt.Errorf("menu %s: %q has parent %q which isn't in the menu", name, e.Identifier, e.Parent)
+		}
+		parent.Children = append(parent.Children, e)
+	}
+
+	if count := sortMenu(root); count != len(entries) {
+		return nil, fmt.Errorf("menu %s: entries can't be their own ancestors", name)
+	}
+	return root, nil
+}
+
+// sortMenu sorts a menu and all of its children, returning how many entries
+// it visited.
+func sortMenu(menu Menu) int {
+	sort.Sort(menu)
+	count := len(menu)
+	for _, e := range menu {
+		count += sortMenu(e.Children)
+	}
+	return count
+}
@@ -0,0 +1,56 @@
+package solarwind
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildMenusNestsAndSortsEntries(t *testing.T) {
+	configured := map[string][]MenuEntry{
+		"main": {
+			{Name: "Blog", URL: "/posts/", Weight: 20},
+			{Name: "Company", URL: "/company.html", Weight: 10, Identifier: "company"},
+		},
+	}
+	pages := []MarkdownPage{
+		{Title: "Careers", RelLink: "careers.html", Menu: PageMenu{Menus: []string{"main"}, Parent: "company", Weight: 2}},
+		{Title: "About", RelLink: "about.html", Menu: PageMenu{Menus: []string{"main"}, Parent: "company", Weight: 1}},
+		{Title: "Privacy", RelLink: "privacy.html", Menu: PageMenu{Menus: []string{"footer"}}},
+	}
+
+	menus, err := BuildMenus(configured, pages, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	main := menus["main"]
+	if len(main) != 2 || main[0].Name != "Company" || main[1].Name != "Blog" {
+		t.Fatalf("expected main menu to be sorted by weight, got %+v", main)
+	}
+	children := main[0].Children
+	if len(children) != 2 || children[0].Name != "About" || children[1].Name != "Careers" {
+		t.Fatalf("expected company to have sorted children, got %+v", children)
+	}
+	if len(menus["footer"]) != 1 || menus["footer"][0].URL != "/privacy.html" {
+		t.Errorf("expected privacy page in the footer menu, got %+v", menus["footer"])
+	}
+
+	current := pages[1]
+	if !children[0].IsActive(current) || children[1].IsActive(current) {
+		t.Error("expected only the about entry to be active")
+	}
+	if !main[0].HasActiveChild(current) || main[1].HasActiveChild(current) {
+		t.Error("expected only company to have an active child")
+	}
+}
+
+func TestBuildMenusRejectsMissingParents(t *testing.T) {
+	pages := []MarkdownPage{
+		{Title: "About", RelLink: "about.html", Menu: PageMenu{Menus: []string{"main"}, Parent: "company"}},
+	}
+
+	_, err := BuildMenus(nil, pages, "")
+	if err == nil || !strings.Contains(err.Error(), `parent "company"`) {
+		t.Errorf("expected a missing parent error, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package solarwind
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/tdewolff/minify"
+	"github.com/tdewolff/minify/css"
+	"github.com/tdewolff/minify/html"
+	"github.com/tdewolff/minify/js"
+	"github.com/tdewolff/minify/json"
+	"github.com/tdewolff/minify/xml"
+)
+
+// MinifyOptions controls minification of the generated site. Nothing is
+// minified unless Enabled is set; each format can then be turned off on its
+// own.
+type MinifyOptions struct {
+	Enabled bool `json:"enabled"`
+	HTML    bool `json:"html"`
+	CSS     bool `json:"css"`
+	JS      bool `json:"js"`
+	JSON    bool `json:"json"`
+	XML     bool `json:"xml"`
+}
+
+// DefaultMinifyOptions minifies every format once minification is enabled.
+var DefaultMinifyOptions = MinifyOptions{
+	HTML: true,
+	CSS:  true,
+	JS:   true,
+	JSON: true,
+	XML:  true,
+}
+
+var minifyMediaTypes = map[string]string{
+	".html": "text/html",
+	".htm":  "text/html",
+	".css":  "text/css",
+	".js":   "application/javascript",
+	".json": "application/json",
+	".xml":  "text/xml",
+	".rss":  "text/xml",
+	".atom": "text/xml",
+}
+
+// OutputMinifier minifies files on their way into the output directory,
+// picking a minifier from the file extension.
+type OutputMinifier struct {
+	m *minify.M
+}
+
+// NewOutputMinifier returns a minifier for the formats turned on in options.
+// Inline styles and scripts in HTML are only minified if CSS and JS are on.
+func NewOutputMinifier(options MinifyOptions) *OutputMinifier {
+	m := minify.New()
+	if !options.Enabled {
+		return &OutputMinifier{m: m}
+	}
+
+	if options.HTML {
+		m.Add("text/html", &html.Minifier{
+			KeepDocumentTags: true,
+			KeepEndTags:      true,
+		})
+	}
+	if options.CSS {
+		m.AddFunc("text/css", css.Minify)
+	}
+	if options.JS {
+		m.AddFunc("application/javascript", js.Minify)
+		m.AddFunc("text/javascript", js.Minify)
+	}
+	if options.JSON {
+		m.AddFunc("application/json", json.Minify)
+	}
+	if options.XML {
+		m.AddFunc("text/xml", xml.Minify)
+	}
+
+	return &OutputMinifier{m: m}
+}
+
+// MediaType returns the media type filename will be minified as, or an empty
+// string if it won't be minified at all. Files that are already minified,
+// like jquery.min.js, are left alone.
+func (o *OutputMinifier) MediaType(filename string) string {
+	base := path.Base(filename)
+	if strings.Contains(base, ".min.") {
+		return ""
+	}
+
+	mediatype, ok := minifyMediaTypes[strings.ToLower(path.Ext(base))]
+	if !ok {
+		return ""
+	}
+	if _, _, fn := o.m.Match(mediatype); fn == nil {
+		return ""
+	}
+	return mediatype
+}
+
+// Bytes minifies content according to the extension of filename.
+func (o *OutputMinifier) Bytes(filename string, content []byte) ([]byte, error) {
+	mediatype := o.MediaType(filename)
+	if mediatype == "" {
+		return content, nil
+	}
+	return o.m.Bytes(mediatype, content)
+}
+
+// WriteFile is ioutil.WriteFile with minification.
+func (o *OutputMinifier) WriteFile(filename string, content []byte, perm os.FileMode) error {
+	minified, err := o.Bytes(filename, content)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, minified, perm)
+}
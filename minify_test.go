@@ -0,0 +1,42 @@
+package solarwind
+
+import (
+	"testing"
+)
+
+func TestOutputMinifierHonoursToggles(t *testing.T) {
+	options := DefaultMinifyOptions
+	options.Enabled = true
+	options.CSS = false
+	output := NewOutputMinifier(options)
+
+	cases := []struct {
+		filename string
+		content  string
+		expected string
+	}{
+		{"index.html", "<html>\n  <body>\n    <p>  hello  </p>\n  </body>\n</html>\n", "<html><body><p>hello</p></body></html>"},
+		{"site.css", "body {\n  color: red;\n}\n", "body {\n  color: red;\n}\n"},
+		{"feed.xml", "<feed>\n  <title>x</title>\n</feed>\n", "<feed><title>x</title></feed>"},
+		{"index.json", "{\n  \"a\": [1, 2]\n}\n", `{"a":[1,2]}`},
+		{"jquery.min.js", "var a = 1;\n", "var a = 1;\n"},
+		{"image.svg", "<svg>\n</svg>\n", "<svg>\n</svg>\n"},
+	}
+
+	for _, c := range cases {
+		minified, err := output.Bytes(c.filename, []byte(c.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(minified) != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.filename, c.expected, minified)
+		}
+	}
+}
+
+func TestOutputMinifierIsOffByDefault(t *testing.T) {
+	output := NewOutputMinifier(NewConfig().Minify)
+	if mediatype := output.MediaType("index.html"); mediatype != "" {
+		t.Errorf("expected nothing to be minified by default, got %s", mediatype)
+	}
+}
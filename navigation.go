@@ -0,0 +1,89 @@
+package solarwind
+
+import "strings"
+
+// Taxonomies are the page headers that file posts under terms. Posts sharing
+// a term are linked to each other the same way posts in a section are.
+var Taxonomies = []string{"category", "tags"}
+
+// neighbours are the pages either side of a page in some ordered list.
+type neighbours struct {
+	prev *MarkdownPage
+	next *MarkdownPage
+}
+
+// Terms returns the terms page is filed under in taxonomy.
+func (p MarkdownPage) Terms(taxonomy string) []string {
+	switch taxonomy {
+	case "category":
+		if p.Category != "" {
+			return []string{p.Category}
+		}
+	case "tags":
+		return p.Tags
+	}
+	return nil
+}
+
+// PrevIn returns the page before this one among the pages filed under term
+// in taxonomy, or nil if this is the first:
+//
+//	{{ with .CurrentPage.PrevIn "category" .CurrentPage.Category }}
+//	  <a href="/{{ .RelLink }}">{{ .Title }}</a>
+//	{{ end }}
+func (p MarkdownPage) PrevIn(taxonomy, term string) *MarkdownPage {
+	return p.terms[termKey(taxonomy, term)].prev
+}
+
+// NextIn returns the page after this one among the pages filed under term in
+// taxonomy, or nil if this is the last.
+func (p MarkdownPage) NextIn(taxonomy, term string) *MarkdownPage {
+	return p.terms[termKey(taxonomy, term)].next
+}
+
+func termKey(taxonomy, term string) string {
+	return taxonomy + "\x00" + strings.ToLower(term)
+}
+
+// LinkNeighbours points Prev and Next of every page at the pages either side
+// of it, and does the same for every taxonomy term the pages are filed under.
+// pages must already be filtered and sorted; the pointers are into pages
+// itself, so it must not be copied into a new slice afterwards.
+func LinkNeighbours(pages []MarkdownPage) {
+	terms := map[string][]*MarkdownPage{}
+	for i := range pages {
+		page := &pages[i]
+		page.Prev, page.Next = nil, nil
+		page.terms = map[string]neighbours{}
+		if i > 0 {
+			page.Prev = &pages[i-1]
+		}
+		if i < len(pages)-1 {
+			page.Next = &pages[i+1]
+		}
+
+		for _, taxonomy := range Taxonomies {
+			seen := map[string]bool{}
+			for _, term := range page.Terms(taxonomy) {
+				key := termKey(taxonomy, term)
+				if !seen[key] {
+					seen[key] = true
+					terms[key] = append(terms[key], page)
+				}
+			}
+		}
+	}
+
+	for key, list := range terms {
+		for i, page := range list {
+			var n neighbours
+			if i > 0 {
+				n.prev = list[i-1]
+			}
+			if i < len(list)-1 {
+				n.next = list[i+1]
+			}
+			page.terms[key] = n
+		}
+	}
+}
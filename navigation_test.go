@@ -0,0 +1,46 @@
+package solarwind
+
+import "testing"
+
+func TestLinkNeighbours(t *testing.T) {
+	pages := []MarkdownPage{
+		{Title: "Newest", Category: "computers", Tags: []string{"go"}},
+		{Title: "Middle", Category: "food", Tags: []string{"Go", "pasta"}},
+		{Title: "Oldest", Category: "Computers", Tags: []string{"pasta", "pasta"}},
+	}
+	LinkNeighbours(pages)
+
+	if pages[0].Prev != nil || pages[0].Next != &pages[1] || pages[1].Prev != &pages[0] ||
+		pages[1].Next != &pages[2] || pages[2].Next != nil {
+		t.Error("section neighbours are wrong")
+	}
+
+	if next := pages[0].NextIn("category", "computers"); next != &pages[2] {
+		t.Errorf("expected Oldest after Newest in computers, got %v", next)
+	}
+	if prev := pages[2].PrevIn("category", "COMPUTERS"); prev != &pages[0] {
+		t.Errorf("expected Newest before Oldest in computers, got %v", prev)
+	}
+	if pages[1].PrevIn("category", "food") != nil || pages[1].NextIn("category", "food") != nil {
+		t.Error("expected Middle to be alone in food")
+	}
+	if pages[0].NextIn("tags", "go") != &pages[1] || pages[1].NextIn("tags", "pasta") != &pages[2] {
+		t.Error("tag neighbours are wrong")
+	}
+	if pages[2].PrevIn("tags", "pasta") != &pages[1] || pages[2].NextIn("tags", "pasta") != nil {
+		t.Error("repeated tags should only be counted once")
+	}
+	if pages[0].PrevIn("tags", "nope") != nil {
+		t.Error("expected no neighbours for an unknown term")
+	}
+}
+
+func TestMarkdownPageDraft(t *testing.T) {
+	page, err := NewMarkdownPage("post", "###\ntitle: Draft\ndraft: true\n###\n\nbody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.Draft {
+		t.Error("expected the page to be a draft")
+	}
+}
@@ -0,0 +1,59 @@
+package solarwind
+
+//go:generate go run starter_gen.go
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NewSite writes the starter Solarwindfile, templates and content into dir,
+// along with the empty directories a site needs. dir must not exist yet or be
+// empty.
+func NewSite(dir string) error {
+	if entries, err := ioutil.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s already exists and is not empty", dir)
+	}
+
+	for _, d := range []string{DefaultLayout.ContentDir + "/posts", DefaultLayout.TemplateDir, DefaultLayout.StaticDir} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(d)), 0755); err != nil {
+			return err
+		}
+	}
+
+	for name, content := range starterFiles {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewPost creates a post in dir named after the slug of title, with the
+// header filled in. It returns the path of the new file.
+func NewPost(dir, title string, date time.Time) (string, error) {
+	slug := PageSlug(title)
+	if slug == "" {
+		return "", fmt.Errorf("%q doesn't make a usable filename", title)
+	}
+
+	p := filepath.Join(dir, slug+"."+TypeMarkdown)
+	if _, err := os.Stat(p); err == nil {
+		return "", fmt.Errorf("%s already exists", p)
+	}
+
+	header := fmt.Sprintf("###\ntitle: %s\ndate: %s\ncategory:\n###\n\n", title, date.Format(time.RFC822))
+	if err := ioutil.WriteFile(p, []byte(header), 0644); err != nil {
+		return "", err
+	}
+
+	return p, nil
+}
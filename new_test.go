@@ -0,0 +1,62 @@
+package solarwind
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStarterFilesMatchStarterDir(t *testing.T) {
+	for name, content := range starterFiles {
+		onDisk, err := ioutil.ReadFile(filepath.Join("starter", filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(onDisk) != content {
+			t.Errorf("starter/%s has changed, run `go generate` to update starter_files.go", name)
+		}
+	}
+}
+
+func TestNewSiteAndPost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "my-site")
+	if err := NewSite(root); err != nil {
+		t.Fatal(err)
+	}
+	site, err := Open(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewSite(root); err == nil {
+		t.Error("expected NewSite to refuse a directory that isn't empty")
+	}
+
+	p, err := NewPost(site.PostsDir, "My First Post", time.Date(2015, 3, 6, 13, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != filepath.Join(root, "content", "posts", "my-first-post.md") {
+		t.Errorf("unexpected post path %s", p)
+	}
+
+	content, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := NewMarkdownPage("my-first-post", string(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "My First Post" || page.Date.Day() != 6 || !strings.HasSuffix(page.RelLink, "my-first-post.html") {
+		t.Errorf("unexpected post %+v", page)
+	}
+}
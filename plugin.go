@@ -0,0 +1,131 @@
+package solarwind
+
+import "fmt"
+
+// Plugin is anything registered with Site.Use. A plugin implements any of the
+// hook interfaces below, and the build calls the hooks it implements at these
+// points, in the order the plugins were registered:
+//
+//	ConfigLoaded  once Load has resolved the layout and theme
+//	PageParsed    for every markdown post, page and collection page, after
+//	              its header is read and drafts are dropped
+//	BeforeRender  for every markdown page, before shortcodes are expanded
+//	              and the markdown is rendered
+//	AfterRender   for every markdown page, once FinalHTML is set
+//	BeforeWrite   for every page rendered from a template, before it is
+//	              minified and written
+//	AfterBuild    once everything has been written
+//
+// Multilingual sites run the page hooks once for every language. An error
+// from any hook stops the build.
+type Plugin interface{}
+
+// ConfigLoadedHook can change the config before anything is built, or
+// reject it.
+type ConfigLoadedHook interface {
+	ConfigLoaded(site *Site) error
+}
+
+// PageParsedHook can validate or change the header of a page.
+type PageParsedHook interface {
+	PageParsed(site *Site, page *MarkdownPage) error
+}
+
+// BeforeRenderHook can change the RawMarkdown of a page.
+type BeforeRenderHook interface {
+	BeforeRender(site *Site, page *MarkdownPage) error
+}
+
+// AfterRenderHook can change the FinalHTML of a page, or record things about
+// it in its Params for the templates. Params is nil for pages whose header
+// has no params of its own.
+type AfterRenderHook interface {
+	AfterRender(site *Site, page *MarkdownPage) error
+}
+
+// BeforeWriteHook is given the path and contents of every page about to be
+// written and returns the contents to write instead.
+type BeforeWriteHook interface {
+	BeforeWrite(site *Site, file string, content []byte) ([]byte, error)
+}
+
+// AfterBuildHook is called with the output directory, where it can add files
+// of its own.
+type AfterBuildHook interface {
+	AfterBuild(site *Site, out string) error
+}
+
+// Use registers plugins with the site. Plugins that implement ConfigLoaded
+// need to be registered before Load is called; Open takes them for that
+// reason.
+func (s *Site) Use(plugins ...Plugin) {
+	s.plugins = append(s.plugins, plugins...)
+}
+
+func (s *Site) configLoaded() error {
+	for _, p := range s.plugins {
+		if h, ok := p.(ConfigLoadedHook); ok {
+			if err := h.ConfigLoaded(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Site) pageParsed(page *MarkdownPage) error {
+	for _, p := range s.plugins {
+		if h, ok := p.(PageParsedHook); ok {
+			if err := h.PageParsed(s, page); err != nil {
+				return fmt.Errorf("%s: %s", page.RelLink, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Site) beforeRender(page *MarkdownPage) error {
+	for _, p := range s.plugins {
+		if h, ok := p.(BeforeRenderHook); ok {
+			if err := h.BeforeRender(s, page); err != nil {
+				return fmt.Errorf("%s: %s", page.RelLink, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Site) afterRender(page *MarkdownPage) error {
+	for _, p := range s.plugins {
+		if h, ok := p.(AfterRenderHook); ok {
+			if err := h.AfterRender(s, page); err != nil {
+				return fmt.Errorf("%s: %s", page.RelLink, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Site) beforeWrite(file string, content []byte) ([]byte, error) {
+	for _, p := range s.plugins {
+		if h, ok := p.(BeforeWriteHook); ok {
+			var err error
+			content, err = h.BeforeWrite(s, file, content)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", file, err)
+			}
+		}
+	}
+	return content, nil
+}
+
+func (s *Site) afterBuild(out string) error {
+	for _, p := range s.plugins {
+		if h, ok := p.(AfterBuildHook); ok {
+			if err := h.AfterBuild(s, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
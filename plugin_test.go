@@ -0,0 +1,150 @@
+package solarwind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recorder implements every hook, recording when it's called.
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) ConfigLoaded(site *Site) error {
+	r.events = append(r.events, "config")
+	site.Config.SiteTitle = "Recorded"
+	return nil
+}
+
+func (r *recorder) PageParsed(site *Site, page *MarkdownPage) error {
+	r.events = append(r.events, "parsed "+page.RelLink)
+	return nil
+}
+
+func (r *recorder) BeforeRender(site *Site, page *MarkdownPage) error {
+	r.events = append(r.events, "before render "+page.RelLink)
+	page.RawMarkdown += "\n\nAdded by a plugin."
+	return nil
+}
+
+func (r *recorder) AfterRender(site *Site, page *MarkdownPage) error {
+	r.events = append(r.events, "after render "+page.RelLink)
+	if page.Params == nil {
+		page.Params = map[string]interface{}{}
+	}
+	page.Params["words"] = len(strings.Fields(page.RawMarkdown))
+	return nil
+}
+
+func (r *recorder) BeforeWrite(site *Site, file string, content []byte) ([]byte, error) {
+	r.events = append(r.events, "write "+filepath.Base(file))
+	return append(content, "<!-- recorded -->"...), nil
+}
+
+func (r *recorder) AfterBuild(site *Site, out string) error {
+	r.events = append(r.events, "done")
+	return ioutil.WriteFile(filepath.Join(out, "recorded.txt"), []byte(fmt.Sprint(len(r.events))), 0644)
+}
+
+// requireCategory fails pages without a category.
+type requireCategory struct{}
+
+func (requireCategory) PageParsed(site *Site, page *MarkdownPage) error {
+	if page.Category == "" {
+		return errors.New("a category is required")
+	}
+	return nil
+}
+
+func newPluginTestSite(t *testing.T, plugins ...Plugin) (*Site, string) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewSite(root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPost(filepath.Join(root, "content", "posts"), "Hello World", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	site, err := Open(root, "", plugins...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return site, root
+}
+
+func TestPluginHooks(t *testing.T) {
+	r := &recorder{}
+	site, root := newPluginTestSite(t, r)
+	defer os.RemoveAll(root)
+
+	if site.Config.SiteTitle != "Recorded" {
+		t.Errorf("expected ConfigLoaded to run when the site is opened, got %q", site.Config.SiteTitle)
+	}
+	if err := site.Build(context.Background(), site.DestinationDir); err != nil {
+		t.Fatal(err)
+	}
+
+	index := func(event string) int {
+		for i, e := range r.events {
+			if e == event {
+				return i
+			}
+		}
+		t.Errorf("expected %q in %q", event, r.events)
+		return -1
+	}
+	order := []string{
+		"config",
+		"parsed posts/hello-world.html",
+		"parsed index.html",
+		"before render posts/hello-world.html",
+		"after render posts/hello-world.html",
+		"write hello-world.html",
+		"done",
+	}
+	for i := 1; i < len(order); i++ {
+		if index(order[i-1]) > index(order[i]) {
+			t.Errorf("expected %q before %q, got %q", order[i-1], order[i], r.events)
+		}
+	}
+	if r.events[len(r.events)-1] != "done" {
+		t.Errorf("expected AfterBuild to be last, got %q", r.events)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(site.DestinationDir, "posts", "hello-world.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"Added by a plugin.", "<!-- recorded -->"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("expected %q in:\n%s", expected, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(site.DestinationDir, "recorded.txt")); err != nil {
+		t.Errorf("expected AfterBuild to add a file: %s", err)
+	}
+}
+
+func TestPluginErrorsStopTheBuild(t *testing.T) {
+	site, root := newPluginTestSite(t)
+	defer os.RemoveAll(root)
+	site.Use(requireCategory{})
+
+	err := site.Build(context.Background(), site.DestinationDir)
+	if err == nil || !strings.Contains(err.Error(), "a category is required") {
+		t.Fatalf("expected the plugin to fail the build, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(site.DestinationDir, "posts", "hello-world.html")); !os.IsNotExist(err) {
+		t.Error("expected nothing to be written after a plugin failed")
+	}
+}
@@ -0,0 +1,87 @@
+package solarwind
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// RefPrefix starts a markdown link to another content file.
+const RefPrefix = "ref:"
+
+// RefResolver finds the pages built from content files, so content can link
+// to other content by file instead of by URL:
+//
+//	[My post](ref:posts/my-post.md)
+//	<a href="{{ relref "posts/my-post.md" }}">My post</a>
+//
+// Files are relative to the content directory. On multilingual sites they
+// resolve to the page in the language being built, unless they name a
+// language themselves, like posts/my-post.fr.md or fr/about.md.
+type RefResolver struct {
+	BaseURL string
+
+	// pages maps a language to its pages by translation key.
+	pages map[string]map[string]*MarkdownPage
+}
+
+// NewRefResolver indexes pages, which holds the pages of each of languages.
+func NewRefResolver(languages []*Language, pages [][]*MarkdownPage, baseURL string) *RefResolver {
+	r := &RefResolver{BaseURL: baseURL, pages: map[string]map[string]*MarkdownPage{}}
+	for i, language := range languages {
+		index := map[string]*MarkdownPage{}
+		for _, page := range pages[i] {
+			index[page.translationKey] = page
+		}
+		r.pages[language.Code] = index
+	}
+	return r
+}
+
+// RelRef returns the root relative URL of the page built from file, in
+// language unless file names another one.
+func (r *RefResolver) RelRef(language, file string) (string, error) {
+	page, fragment, err := r.find(language, file)
+	if err != nil {
+		return "", err
+	}
+	return "/" + page.RelLink + fragment, nil
+}
+
+// Ref is RelRef with the site's base_url in front.
+func (r *RefResolver) Ref(language, file string) (string, error) {
+	page, fragment, err := r.find(language, file)
+	if err != nil {
+		return "", err
+	}
+	if r.BaseURL == "" {
+		return "/" + page.RelLink + fragment, nil
+	}
+	return strings.TrimSuffix(r.BaseURL, "/") + "/" + page.RelLink + fragment, nil
+}
+
+func (r *RefResolver) find(language, file string) (*MarkdownPage, string, error) {
+	var fragment string
+	if i := strings.Index(file, "#"); i >= 0 {
+		file, fragment = file[:i], file[i:]
+	}
+
+	p := strings.TrimPrefix(path.Clean("/"+file), "/")
+	if parts := strings.SplitN(p, "/", 2); len(parts) == 2 && r.pages[parts[0]] != nil {
+		language, p = parts[0], parts[1]
+	}
+	if code := fileLanguage(p); r.pages[code] != nil {
+		language = code
+	}
+
+	key := strings.Split(path.Base(p), ".")[0]
+	if dir := path.Dir(p); dir != "." {
+		key = dir + "/" + key
+	}
+
+	page, ok := r.pages[language][key]
+	if !ok {
+		return nil, "", fmt.Errorf("ref %s: no page is built from that file", file)
+	}
+	return page, fragment, nil
+}
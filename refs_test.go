@@ -0,0 +1,73 @@
+package solarwind
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRefResolver(t *testing.T) {
+	en := []MarkdownPage{
+		{RelLink: "en/posts/hello.html", translationKey: "posts/hello"},
+		{RelLink: "en/about.html", translationKey: "about"},
+		{RelLink: "en/products/solar-panel.html", translationKey: "products/solar-panel"},
+	}
+	fr := []MarkdownPage{
+		{RelLink: "fr/posts/bonjour.html", translationKey: "posts/hello"},
+	}
+	languages := []*Language{{Code: "en"}, {Code: "fr"}}
+	refs := NewRefResolver(languages, [][]*MarkdownPage{{&en[0], &en[1], &en[2]}, {&fr[0]}}, "https://example.com/")
+
+	tests := []struct {
+		language, file, expected string
+	}{
+		{"en", "posts/hello.md", "/en/posts/hello.html"},
+		{"fr", "posts/hello.md", "/fr/posts/bonjour.html"},
+		{"en", "/posts/hello.markdown#intro", "/en/posts/hello.html#intro"},
+		{"en", "posts/hello.fr.md", "/fr/posts/bonjour.html"},
+		{"en", "fr/posts/hello.md", "/fr/posts/bonjour.html"},
+		{"fr", "about.en.md", "/en/about.html"},
+		{"en", "products/solar-panel", "/en/products/solar-panel.html"},
+	}
+	for _, test := range tests {
+		got, err := refs.RelRef(test.language, test.file)
+		if err != nil {
+			t.Errorf("%s %s: %s", test.language, test.file, err)
+		} else if got != test.expected {
+			t.Errorf("%s %s: expected %s, got %s", test.language, test.file, test.expected, got)
+		}
+	}
+
+	if got, err := refs.Ref("en", "about.md"); err != nil || got != "https://example.com/en/about.html" {
+		t.Errorf("unexpected ref %s (%v)", got, err)
+	}
+	if _, err := refs.RelRef("fr", "about.md"); err == nil {
+		t.Error("expected about.md to have no French page")
+	}
+}
+
+func TestRenderMarkdownRefs(t *testing.T) {
+	refs := func(file string) (string, error) {
+		if file == "posts/a.md" {
+			return "/posts/a.html", nil
+		}
+		return "", refError(file)
+	}
+
+	rendered, err := RenderMarkdown("[A](ref:posts/a.md) and [Google](https://google.com)\n", nil, refs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rendered, `<a href="/posts/a.html">A</a>`) || !strings.Contains(rendered, `href="https://google.com"`) {
+		t.Errorf("unexpected markdown %s", rendered)
+	}
+
+	if _, err := RenderMarkdown("[Gone](ref:posts/gone.md)\n", nil, refs); err == nil {
+		t.Error("expected a dangling ref to be an error")
+	}
+}
+
+type refError string
+
+func (e refError) Error() string {
+	return "no page for " + string(e)
+}
@@ -0,0 +1,103 @@
+package solarwind
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// RelatedOptions controls how related posts are ranked. A post is related if
+// it shares at least one tag or its category with the current post. Its score
+// is the weighted sum of the number of shared tags, whether the category
+// matches and how recent it is.
+type RelatedOptions struct {
+	// Limit is the most related posts a post gets.
+	Limit int `json:"limit"`
+
+	TagWeight      float64 `json:"tag_weight"`
+	CategoryWeight float64 `json:"category_weight"`
+	RecencyWeight  float64 `json:"recency_weight"`
+
+	// RecencyHalfLife is how many days older than the newest post a post
+	// has to be for its recency score to halve.
+	RecencyHalfLife float64 `json:"recency_half_life"`
+}
+
+var DefaultRelatedOptions = RelatedOptions{
+	Limit:           5,
+	TagWeight:       1,
+	CategoryWeight:  1,
+	RecencyWeight:   0.5,
+	RecencyHalfLife: 180,
+}
+
+// ComputeRelated fills in Related for every post.
+func ComputeRelated(posts Posts, options RelatedOptions) {
+	var newest float64
+	for _, post := range posts {
+		if !post.Date.IsZero() {
+			newest = math.Max(newest, float64(post.Date.Unix()))
+		}
+	}
+
+	recency := func(post MarkdownPage) float64 {
+		if post.Date.IsZero() || options.RecencyHalfLife <= 0 {
+			return 0
+		}
+		days := (newest - float64(post.Date.Unix())) / (24 * 60 * 60)
+		return math.Pow(0.5, days/options.RecencyHalfLife)
+	}
+
+	type candidate struct {
+		post  *MarkdownPage
+		score float64
+	}
+
+	for i := range posts {
+		current := &posts[i]
+		tags := map[string]bool{}
+		for _, tag := range current.Tags {
+			tags[strings.ToLower(tag)] = true
+		}
+
+		var candidates []candidate
+		for j := range posts {
+			if i == j {
+				continue
+			}
+			other := &posts[j]
+
+			shared := 0
+			for _, tag := range other.Tags {
+				if tags[strings.ToLower(tag)] {
+					shared++
+				}
+			}
+			sameCategory := current.Category != "" && strings.EqualFold(current.Category, other.Category)
+			if shared == 0 && !sameCategory {
+				continue
+			}
+
+			score := options.TagWeight*float64(shared) + options.RecencyWeight*recency(*other)
+			if sameCategory {
+				score += options.CategoryWeight
+			}
+			candidates = append(candidates, candidate{other, score})
+		}
+
+		sort.SliceStable(candidates, func(a, b int) bool {
+			if candidates[a].score != candidates[b].score {
+				return candidates[a].score > candidates[b].score
+			}
+			return candidates[a].post.Date.After(candidates[b].post.Date)
+		})
+		if options.Limit >= 0 && len(candidates) > options.Limit {
+			candidates = candidates[:options.Limit]
+		}
+
+		current.Related = nil
+		for _, c := range candidates {
+			current.Related = append(current.Related, c.post)
+		}
+	}
+}
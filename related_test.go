@@ -0,0 +1,60 @@
+package solarwind
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeRelated(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2015, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+	posts := Posts{
+		{Title: "Current", Category: "computers", Tags: []string{"go", "web"}, Date: day(20)},
+		{Title: "Both tags", Tags: []string{"Go", "web"}, Date: day(1)},
+		{Title: "One tag", Tags: []string{"go"}, Date: day(10)},
+		{Title: "Category", Category: "Computers", Date: day(15)},
+		{Title: "Newer category", Category: "computers", Date: day(19)},
+		{Title: "Unrelated", Category: "food", Tags: []string{"pasta"}, Date: day(20)},
+	}
+
+	options := DefaultRelatedOptions
+	options.Limit = 4
+	options.TagWeight = 2
+	ComputeRelated(posts, options)
+
+	var titles []string
+	for _, related := range posts[0].Related {
+		titles = append(titles, related.Title)
+	}
+	expected := []string{"Both tags", "One tag", "Newer category", "Category"}
+	if len(titles) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, titles)
+	}
+	for i := range expected {
+		if titles[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, titles)
+		}
+	}
+
+	if len(posts[5].Related) != 0 {
+		t.Errorf("expected no related posts, got %d", len(posts[5].Related))
+	}
+
+	options.Limit = 1
+	options.TagWeight = 0
+	ComputeRelated(posts, options)
+	if len(posts[0].Related) != 1 || posts[0].Related[0].Title != "Newer category" {
+		t.Errorf("expected the category to win without tag weight, got %v", posts[0].Related[0].Title)
+	}
+}
+
+func TestMarkdownPageTags(t *testing.T) {
+	page, err := NewMarkdownPage("post", "###\ntitle: Tagged\ntags: go,  static sites ,\n###\n\nbody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Tags) != 2 || page.Tags[0] != "go" || page.Tags[1] != "static sites" {
+		t.Errorf("unexpected tags %q", page.Tags)
+	}
+}
@@ -0,0 +1,316 @@
+package solarwind
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultS3Region = "us-east-1"
+
+	// emptyPayloadHash is the SHA-256 of an empty body.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// S3Target deploys to an S3 compatible bucket. Requests are signed with AWS
+// Signature Version 4, which MinIO, DigitalOcean Spaces and friends accept
+// too.
+type S3Target struct {
+	Bucket          string
+	Region          string
+	Endpoint        string
+	Prefix          string
+	PathStyle       bool
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	Client *http.Client
+}
+
+func NewS3Target(options DeployOptions) *S3Target {
+	t := &S3Target{
+		Bucket:          options.Bucket,
+		Region:          options.Region,
+		Endpoint:        options.Endpoint,
+		Prefix:          strings.TrimPrefix(options.Prefix, "/"),
+		PathStyle:       options.PathStyle,
+		AccessKeyID:     options.AccessKeyID,
+		SecretAccessKey: options.SecretAccessKey,
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Client:          &http.Client{Timeout: time.Minute},
+	}
+	if t.Region == "" {
+		t.Region = DefaultS3Region
+	}
+	if t.Endpoint == "" {
+		t.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", t.Region)
+	}
+	if t.Prefix != "" && !strings.HasSuffix(t.Prefix, "/") {
+		t.Prefix += "/"
+	}
+	if t.AccessKeyID == "" {
+		t.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if t.SecretAccessKey == "" {
+		t.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	return t
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		ETag string `xml:"ETag"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// List returns the ETag of every object under the prefix. ETags are the MD5
+// of the object for everything but multipart uploads, which the deploy never
+// makes, so they only mismatch when an object really changed.
+func (t *S3Target) List() (map[string]string, error) {
+	files := map[string]string{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {t.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		body, err := t.do("GET", "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result s3ListResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("could not read the bucket listing: %s", err)
+		}
+		for _, object := range result.Contents {
+			files[strings.TrimPrefix(object.Key, t.Prefix)] = strings.Trim(object.ETag, `"`)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return files, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (t *S3Target) Upload(file DeployFile) error {
+	content, err := ioutil.ReadFile(file.Source)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(content)
+	header := http.Header{}
+	header.Set("Content-Type", file.ContentType)
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	if file.CacheControl != "" {
+		header.Set("Cache-Control", file.CacheControl)
+	}
+	_, err = t.do("PUT", t.Prefix+file.Path, nil, content, header)
+	return err
+}
+
+func (t *S3Target) Delete(p string) error {
+	_, err := t.do("DELETE", t.Prefix+p, nil, nil, nil)
+	return err
+}
+
+// objectURL returns the URL of key in the bucket.
+func (t *S3Target) objectURL(key string, query url.Values) (*url.URL, error) {
+	u, err := url.Parse(t.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if t.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + t.Bucket + "/" + key
+	} else {
+		u.Host = t.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawPath = awsEscapePath(u.Path)
+	u.RawQuery = awsCanonicalQuery(query)
+	return u, nil
+}
+
+// do sends a signed request and returns the response body, or the error S3
+// replied with.
+func (t *S3Target) do(method, key string, query url.Values, body []byte, header http.Header) ([]byte, error) {
+	u, err := t.objectURL(key, query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if t.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", t.SessionToken)
+	}
+
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	signV4(req, payloadHash, t.AccessKeyID, t.SecretAccessKey, t.Region, "s3", time.Now())
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		var e s3Error
+		if xml.Unmarshal(content, &e) == nil && e.Code != "" {
+			return nil, fmt.Errorf("%s %s: %s: %s", method, u.Path, e.Code, e.Message)
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, u.Path, resp.Status)
+	}
+	return content, nil
+}
+
+// signV4 signs req for service in region, setting the X-Amz-Date,
+// X-Amz-Content-Sha256 and Authorization headers. The host and any
+// Content-Type, Content-MD5, Range and X-Amz-* headers are signed.
+func signV4(req *http.Request, payloadHash, accessKey, secretKey, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || name == "content-md5" || name == "range" || strings.HasPrefix(name, "x-amz-") {
+			trimmed := make([]string, len(values))
+			for i, v := range values {
+				trimmed[i] = strings.Join(strings.Fields(v), " ")
+			}
+			headers[name] = strings.Join(trimmed, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := &bytes.Buffer{}
+	for _, name := range names {
+		fmt.Fprintf(canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsEscapePath(req.URL.Path),
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsEscape percent-encodes everything but the unreserved characters, which
+// is the encoding signatures are computed over.
+func awsEscape(s string) string {
+	b := &bytes.Buffer{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func awsEscapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsCanonicalQuery encodes query sorted by key and then value.
+func awsCanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
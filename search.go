@@ -0,0 +1,137 @@
+package solarwind
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	SearchFieldTitle   = "title"
+	SearchFieldTags    = "tags"
+	SearchFieldSummary = "summary"
+	SearchFieldText    = "text"
+)
+
+// SearchOptions controls the search index written for client-side search.
+type SearchOptions struct {
+	Enabled bool `json:"enabled"`
+
+	// Output is where the index is written, relative to the output
+	// directory of each language.
+	Output string `json:"output"`
+
+	// Fields are the fields of each page that go into the index: title,
+	// tags, summary and text.
+	Fields []string `json:"fields"`
+
+	// StopWords are left out of the text of every page.
+	StopWords []string `json:"stop_words"`
+
+	// SummaryWords is how long summaries are for pages without a summary
+	// header.
+	SummaryWords int `json:"summary_words"`
+}
+
+var DefaultSearchOptions = SearchOptions{
+	Enabled:      true,
+	Output:       "search-index.json",
+	Fields:       []string{SearchFieldTitle, SearchFieldTags, SearchFieldSummary, SearchFieldText},
+	StopWords:    strings.Fields(defaultStopWords),
+	SummaryWords: 30,
+}
+
+const defaultStopWords = `a an and are as at be but by for from has have he i if in into is it its
+of on or she so that the their them then there these they this to was we were what when which
+who will with you your`
+
+// SearchDocument is a page in the search index. Fields that weren't asked
+// for are left out.
+type SearchDocument struct {
+	URL     string   `json:"url"`
+	Title   string   `json:"title,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+	Text    string   `json:"text,omitempty"`
+}
+
+var htmlTag = regexp.MustCompile(`(?s)<[^>]*>`)
+
+// BuildSearchIndex turns pages into search documents. The text of a page is
+// its rendered content without markup, lower cased, with every word only
+// appearing once and the stop words taken out, which keeps the index small.
+func BuildSearchIndex(pages []MarkdownPage, options SearchOptions) []SearchDocument {
+	fields := map[string]bool{}
+	for _, field := range options.Fields {
+		fields[field] = true
+	}
+	stop := map[string]bool{}
+	for _, word := range options.StopWords {
+		stop[strings.ToLower(word)] = true
+	}
+
+	docs := make([]SearchDocument, 0, len(pages))
+	for _, page := range pages {
+		words := strings.FieldsFunc(PlainText(string(page.FinalHTML)), func(c rune) bool {
+			return unicode.IsSpace(c)
+		})
+
+		doc := SearchDocument{URL: "/" + page.RelLink}
+		if fields[SearchFieldTitle] {
+			doc.Title = page.Title
+		}
+		if fields[SearchFieldTags] {
+			doc.Tags = page.Tags
+		}
+		if fields[SearchFieldSummary] {
+			if summary, ok := page.Params["summary"]; ok {
+				doc.Summary = fmt.Sprint(summary)
+			} else if len(words) > options.SummaryWords {
+				doc.Summary = strings.Join(words[:options.SummaryWords], " ") + "…"
+			} else {
+				doc.Summary = strings.Join(words, " ")
+			}
+		}
+		if fields[SearchFieldText] {
+			doc.Text = searchText(words, stop)
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// PlainText strips the markup out of rendered HTML.
+func PlainText(content string) string {
+	return html.UnescapeString(htmlTag.ReplaceAllString(content, " "))
+}
+
+func searchText(words []string, stop map[string]bool) string {
+	seen := map[string]bool{}
+	var text []string
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimFunc(word, func(c rune) bool {
+			return !unicode.IsLetter(c) && !unicode.IsNumber(c)
+		}))
+		if word == "" || stop[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		text = append(text, word)
+	}
+	return strings.Join(text, " ")
+}
+
+// WriteSearchIndex writes docs to dest as JSON.
+func WriteSearchIndex(dest string, docs []SearchDocument, output *OutputMinifier) error {
+	b := &bytes.Buffer{}
+	encoder := json.NewEncoder(b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(docs); err != nil {
+		return err
+	}
+	return output.WriteFile(dest, b.Bytes(), 0644)
+}
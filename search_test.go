@@ -0,0 +1,56 @@
+package solarwind
+
+import (
+	"html/template"
+	"testing"
+)
+
+func TestBuildSearchIndex(t *testing.T) {
+	pages := []MarkdownPage{
+		{
+			Title:     "Solar Panels",
+			RelLink:   "posts/solar-panels.html",
+			Tags:      []string{"energy"},
+			FinalHTML: template.HTML("<p>The <b>sun</b> is a star, and the Sun powers panels &amp; more.</p>"),
+		},
+		{
+			Title:     "About",
+			RelLink:   "about.html",
+			Params:    map[string]interface{}{"summary": "Who we are"},
+			FinalHTML: template.HTML("<p>We build things.</p>"),
+		},
+	}
+
+	options := DefaultSearchOptions
+	options.SummaryWords = 4
+	docs := BuildSearchIndex(pages, options)
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(docs))
+	}
+
+	doc := docs[0]
+	if doc.URL != "/posts/solar-panels.html" || doc.Title != "Solar Panels" || len(doc.Tags) != 1 {
+		t.Errorf("unexpected document %+v", doc)
+	}
+	if doc.Summary != "The sun is a…" {
+		t.Errorf("unexpected summary %q", doc.Summary)
+	}
+	if doc.Text != "sun star powers panels more" {
+		t.Errorf("unexpected text %q", doc.Text)
+	}
+	if docs[1].Summary != "Who we are" {
+		t.Errorf("expected the summary header to be used, got %q", docs[1].Summary)
+	}
+
+	options.Fields = []string{SearchFieldTitle}
+	docs = BuildSearchIndex(pages, options)
+	if docs[0].Title == "" || docs[0].Summary != "" || docs[0].Text != "" || docs[0].Tags != nil {
+		t.Errorf("expected only the title, got %+v", docs[0])
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	if text := PlainText("<p>Fish &amp; <a href=\"/chips\">chips</a></p>"); text != " Fish &  chips  " {
+		t.Errorf("unexpected text %q", text)
+	}
+}
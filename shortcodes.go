@@ -0,0 +1,255 @@
+package solarwind
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	shortcodeOpen  = "{{<"
+	shortcodeClose = ">}}"
+)
+
+// ShortcodeDir is where shortcode templates live, relative to the template
+// directories.
+const ShortcodeDir = "shortcodes"
+
+// Shortcode is the context a shortcode template is executed with. Given
+//
+//	{{< figure "images/a.jpg" caption="A photo" >}}
+//
+// the template templates/shortcodes/figure.html can use {{ .Get 0 }} and
+// {{ .Get "caption" }}. Shortcodes with a closing tag get everything between
+// the tags, with any nested shortcodes expanded, as .Inner:
+//
+//	{{< note >}}Don't *panic*{{< /note >}}
+//
+// .Inner is raw markdown; use {{ markdownify .Inner }} to render it.
+type Shortcode struct {
+	Name       string
+	Params     map[string]string
+	Positional []string
+	Inner      template.HTML
+	Page       MarkdownPage
+	Site       *SiteContext
+}
+
+// Get returns a positional param when given a number and a named param when
+// given a string. Missing params are empty.
+func (s *Shortcode) Get(key interface{}) string {
+	switch k := key.(type) {
+	case int:
+		if k >= 0 && k < len(s.Positional) {
+			return s.Positional[k]
+		}
+	case string:
+		return s.Params[k]
+	}
+	return ""
+}
+
+// ShortcodeRenderer expands shortcodes in markdown before it's rendered.
+type ShortcodeRenderer struct {
+	Site  *SiteContext
+	Funcs template.FuncMap
+
+	// TemplateDirs are searched in order for shortcode templates.
+	TemplateDirs []string
+
+	templates map[string]*template.Template
+}
+
+func NewShortcodeRenderer(site *SiteContext, templateDirs []string, funcs template.FuncMap) *ShortcodeRenderer {
+	return &ShortcodeRenderer{
+		Site:         site,
+		Funcs:        funcs,
+		TemplateDirs: templateDirs,
+		templates:    map[string]*template.Template{},
+	}
+}
+
+// Expand replaces every shortcode in rawMarkdown with the output of its
+// template. Shortcodes can be written literally by commenting them out:
+// {{</* note */>}} renders as {{< note >}}.
+func (r *ShortcodeRenderer) Expand(rawMarkdown string, page MarkdownPage) (string, error) {
+	if !strings.Contains(rawMarkdown, shortcodeOpen) {
+		return rawMarkdown, nil
+	}
+
+	out, _, err := r.expand(rawMarkdown, page, "")
+	return out, err
+}
+
+// expand renders input until it reaches the closing tag of the shortcode
+// called closing, returning the output and whatever follows the closing tag.
+func (r *ShortcodeRenderer) expand(input string, page MarkdownPage, closing string) (string, string, error) {
+	b := &bytes.Buffer{}
+	for {
+		start := strings.Index(input, shortcodeOpen)
+		if start < 0 {
+			if closing != "" {
+				return "", "", fmt.Errorf("shortcode %s is never closed", closing)
+			}
+			b.WriteString(input)
+			return b.String(), "", nil
+		}
+		b.WriteString(input[:start])
+
+		tag, rest, err := scanShortcodeTag(input[start+len(shortcodeOpen):])
+		if err != nil {
+			return "", "", err
+		}
+		input = rest
+
+		if strings.HasPrefix(tag, "/*") && strings.HasSuffix(tag, "*/") {
+			b.WriteString(shortcodeOpen + tag[2:len(tag)-2] + shortcodeClose)
+			continue
+		}
+
+		if strings.HasPrefix(tag, "/") {
+			name := strings.TrimSpace(tag[1:])
+			if name != closing {
+				return "", "", fmt.Errorf("unexpected closing shortcode %s", name)
+			}
+			return b.String(), input, nil
+		}
+
+		sc, err := parseShortcodeTag(tag)
+		if err != nil {
+			return "", "", err
+		}
+		sc.Page = page
+		sc.Site = r.Site
+
+		if shortcodeClosingTag(sc.Name).MatchString(input) {
+			var inner string
+			inner, input, err = r.expand(input, page, sc.Name)
+			if err != nil {
+				return "", "", err
+			}
+			sc.Inner = template.HTML(inner)
+		}
+
+		if err := r.render(b, sc); err != nil {
+			return "", "", err
+		}
+	}
+}
+
+func (r *ShortcodeRenderer) render(b *bytes.Buffer, sc *Shortcode) error {
+	t, ok := r.templates[sc.Name]
+	if !ok {
+		content, err := readTemplate(r.TemplateDirs, ShortcodeDir+"/"+sc.Name+".html")
+		if err != nil {
+			return fmt.Errorf("unknown shortcode %s: %s", sc.Name, err)
+		}
+		t, err = template.New(sc.Name).Funcs(r.Funcs).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("shortcode %s: %s", sc.Name, err)
+		}
+		r.templates[sc.Name] = t
+	}
+
+	if err := t.Execute(b, sc); err != nil {
+		return fmt.Errorf("shortcode %s: %s", sc.Name, err)
+	}
+	return nil
+}
+
+func shortcodeClosingTag(name string) *regexp.Regexp {
+	return regexp.MustCompile(`\{\{<\s*/\s*` + regexp.QuoteMeta(name) + `\s*>\}\}`)
+}
+
+// scanShortcodeTag finds the end of the tag that input starts inside of,
+// skipping over quoted params. It returns the tag without its delimiters and
+// whatever follows it.
+func scanShortcodeTag(input string) (string, string, error) {
+	var quote rune
+	for i, c := range input {
+		switch {
+		case quote != 0:
+			if c == quote && (quote == '`' || input[i-1] != '\\') {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case strings.HasPrefix(input[i:], shortcodeClose):
+			return strings.TrimSpace(input[:i]), input[i+len(shortcodeClose):], nil
+		}
+	}
+	return "", "", fmt.Errorf("shortcode %s%s is missing its closing %s", shortcodeOpen, firstLine(input), shortcodeClose)
+}
+
+// parseShortcodeTag splits a tag like `figure "a.jpg" caption="A photo"` into
+// its name, positional and named params.
+func parseShortcodeTag(tag string) (*Shortcode, error) {
+	sc := &Shortcode{Params: map[string]string{}}
+
+	rest := tag
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		var key, value string
+		var err error
+
+		end := strings.IndexFunc(rest, func(c rune) bool {
+			return unicode.IsSpace(c) || c == '=' || c == '"' || c == '`'
+		})
+		if end > 0 && rest[end] == '=' {
+			key = rest[:end]
+			rest = rest[end+1:]
+		}
+
+		value, rest, err = scanShortcodeValue(rest)
+		if err != nil {
+			return nil, fmt.Errorf("shortcode %s%s%s: %s", shortcodeOpen, tag, shortcodeClose, err)
+		}
+
+		switch {
+		case sc.Name == "" && key == "":
+			sc.Name = value
+		case key != "":
+			sc.Params[key] = value
+		default:
+			sc.Positional = append(sc.Positional, value)
+		}
+	}
+
+	if sc.Name == "" {
+		return nil, fmt.Errorf("shortcode %s%s%s has no name", shortcodeOpen, tag, shortcodeClose)
+	}
+	return sc, nil
+}
+
+func scanShortcodeValue(input string) (string, string, error) {
+	if input == "" {
+		return "", "", fmt.Errorf("missing value")
+	}
+
+	if input[0] == '"' || input[0] == '`' {
+		quote := input[0]
+		for i := 1; i < len(input); i++ {
+			if input[i] == quote && (quote == '`' || input[i-1] != '\\') {
+				value, err := strconv.Unquote(input[:i+1])
+				return value, input[i+1:], err
+			}
+		}
+		return "", "", fmt.Errorf("unterminated quote")
+	}
+
+	end := strings.IndexFunc(input, unicode.IsSpace)
+	if end < 0 {
+		end = len(input)
+	}
+	return input[:end], input[end:], nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package solarwind
+
+import (
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShortcodesAreExpanded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, ShortcodeDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"figure": `<figure src="{{ .Get 0 }}">{{ .Get "caption" }}</figure>`,
+		"note":   `<aside title="{{ .Page.Title }}">{{ .Inner }}</aside>`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, ShortcodeDir, name+".html"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := NewShortcodeRenderer(nil, []string{dir}, template.FuncMap{})
+	page := MarkdownPage{Title: "Post"}
+	raw := "Intro\n\n" +
+		`{{< figure "a.jpg" caption="Say \"cheese\" >" >}}` + "\n\n" +
+		"{{< note >}}A {{< figure b.jpg >}} figure{{< /note >}}\n\n" +
+		"`{{</* note */>}}`\n"
+
+	expanded, err := r.Expand(raw, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`<figure src="a.jpg">Say &#34;cheese&#34; &gt;</figure>`,
+		`<aside title="Post">A <figure src="b.jpg"></figure> figure</aside>`,
+		"`{{< note >}}`",
+	} {
+		if !strings.Contains(expanded, expected) {
+			t.Errorf("expected %s in:\n%s", expected, expanded)
+		}
+	}
+}
+
+func TestShortcodeErrors(t *testing.T) {
+	r := NewShortcodeRenderer(nil, nil, template.FuncMap{})
+	for _, raw := range []string{
+		`{{< figure "a.jpg" `,
+		`{{< /note >}}`,
+		`{{< figure caption="unterminated >}}`,
+	} {
+		if _, err := r.Expand(raw, MarkdownPage{}); err == nil {
+			t.Errorf("expected %s to fail", raw)
+		}
+	}
+}
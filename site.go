@@ -0,0 +1,194 @@
+package solarwind
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Site is a solarwind project: a Config and the directories its Layout
+// points at. Make one with New, or with Open for a project on disk, and call
+// Load before building it.
+type Site struct {
+	Config *Config
+
+	// Root is the directory relative layout directories are resolved
+	// against. New sets it to the working directory.
+	Root string
+
+	// ConfigPath is the Solarwindfile the config was read from, if any.
+	ConfigPath string
+
+	// The directories of the project, set by Load.
+	ContentDir     string
+	PostsDir       string
+	DestinationDir string
+	TemplateDir    string
+	StaticDir      string
+	CacheDir       string
+	DataDir        string
+	I18nDir        string
+
+	// Theme is the theme named by the config, or nil. It is set by Load.
+	Theme *Theme
+
+	// Logger is told about the progress of builds. New makes one that
+	// discards everything.
+	Logger *log.Logger
+
+	// plugins are called at the points of the build they hook into.
+	plugins []Plugin
+
+	// aliases are the redirects of the last build, for AliasHandler.
+	aliases struct {
+		sync.RWMutex
+		redirects map[string]string
+	}
+}
+
+// New returns a site for config rooted at the working directory.
+func New(config *Config) *Site {
+	return &Site{
+		Config: config,
+		Root:   ".",
+		Logger: log.New(ioutil.Discard, "", 0),
+	}
+}
+
+// Open reads the project in source. If source is empty the project is found
+// by searching upward from the working directory. env selects an environment
+// file to merge over the Solarwindfile. plugins are registered before the
+// site is loaded, so it's ready to be built.
+func Open(source, env string, plugins ...Plugin) (*Site, error) {
+	var root string
+	if source != "" {
+		dir, err := filepath.Abs(source)
+		if err != nil {
+			return nil, err
+		}
+		root = dir
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		dir, err := FindProjectRoot(cwd)
+		if err != nil {
+			return nil, err
+		}
+		root = dir
+	}
+
+	configPath, ok := FindSolarwindfile(root, Solarwindfile)
+	if !ok {
+		return nil, fmt.Errorf("%s does not exist. It must exist to continue generating a site.", path.Join(root, Solarwindfile))
+	}
+
+	config, err := LoadConfig(configPath, env)
+	if err != nil {
+		return nil, err
+	}
+
+	s := New(config)
+	s.Root = root
+	s.ConfigPath = configPath
+	s.Use(plugins...)
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load resolves the layout of the config against Root, loads the theme and
+// makes sure the directories a build needs exist. It finishes with the
+// ConfigLoaded hooks.
+func (s *Site) Load() error {
+	root, err := filepath.Abs(s.Root)
+	if err != nil {
+		return err
+	}
+	layout := s.Config.Layout
+
+	resolve := func(dir, fallback string) string {
+		if dir == "" {
+			dir = fallback
+		}
+		if filepath.IsAbs(dir) {
+			return filepath.Clean(dir)
+		}
+		return path.Join(root, dir)
+	}
+
+	s.ContentDir = resolve(layout.ContentDir, DefaultLayout.ContentDir)
+	s.PostsDir = path.Join(s.ContentDir, "posts")
+	s.DestinationDir = resolve(layout.OutputDir, DefaultLayout.OutputDir)
+	s.TemplateDir = resolve(layout.TemplateDir, DefaultLayout.TemplateDir)
+	s.StaticDir = resolve(layout.StaticDir, DefaultLayout.StaticDir)
+	s.CacheDir = resolve(layout.CacheDir, DefaultLayout.CacheDir)
+	s.DataDir = resolve(layout.DataDir, DefaultLayout.DataDir)
+	s.I18nDir = resolve(layout.I18nDir, DefaultLayout.I18nDir)
+
+	s.Theme = nil
+	required := []string{s.ContentDir, s.PostsDir}
+	if s.Config.Theme != "" {
+		theme, err := LoadTheme(path.Join(resolve(layout.ThemesDir, DefaultLayout.ThemesDir), s.Config.Theme))
+		if err != nil {
+			return err
+		}
+		s.Theme = theme
+		s.Config.Params = theme.MergeParams(s.Config.Params)
+	} else {
+		required = append(required, s.TemplateDir)
+	}
+
+	// Sanity check. Make sure a couple of these things exist
+	for _, node := range required {
+		if _, err := os.Stat(node); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("%s does not exist. It must exist to continue generating a site.", node)
+			}
+			return err
+		}
+	}
+
+	return s.configLoaded()
+}
+
+// checkOutputDir returns an error if out is, or contains, the project or any
+// of the directories it's built from. Builds empty the output directory, so
+// that would delete them.
+func (s *Site) checkOutputDir(out string) error {
+	out, err := filepath.Abs(out)
+	if err != nil {
+		return err
+	}
+	root, err := filepath.Abs(s.Root)
+	if err != nil {
+		return err
+	}
+
+	dirs := []string{root, s.ContentDir, s.PostsDir, s.TemplateDir, s.StaticDir, s.DataDir, s.I18nDir}
+	if s.Theme != nil {
+		dirs = append(dirs, s.Theme.Dir)
+	}
+	for _, dir := range dirs {
+		if dir != "" && pathContains(out, dir) {
+			return fmt.Errorf("%s can't be the output directory, building the site would delete %s", out, dir)
+		}
+	}
+	return nil
+}
+
+// pathContains reports whether p is dir or somewhere inside it.
+func pathContains(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,61 @@
+package solarwind
+
+import (
+	"fmt"
+	"path"
+	"sort"
+)
+
+const (
+	SlugCollisionsError  = "error"
+	SlugCollisionsSuffix = "suffix"
+)
+
+// UniqueSlugs makes sure no two pages share a slug. Pages are visited in
+// order of their source files, so the first keeps its slug and, if suffix is
+// set, the others become slug-2, slug-3 and so on. Otherwise a shared slug is
+// an error.
+func UniqueSlugs(pages []MarkdownPage, suffix bool) error {
+	order := make([]int, len(pages))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return pages[order[i]].sourceFile < pages[order[j]].sourceFile
+	})
+
+	taken := map[string]string{}
+	for _, i := range order {
+		page := &pages[i]
+		slug := page.Slug
+		if other, ok := taken[slug]; ok {
+			if !suffix {
+				return fmt.Errorf("%s and %s both have the slug %q. Change one of their titles, or set build.slug_collisions to suffix", other, page.sourceFile, slug)
+			}
+			for n := 2; ; n++ {
+				if _, ok := taken[fmt.Sprintf("%s-%d", slug, n)]; !ok {
+					slug = fmt.Sprintf("%s-%d", slug, n)
+					break
+				}
+			}
+			page.Slug = slug
+			page.RelLink = path.Join(path.Dir(page.RelLink), slug+".html")
+			page.DestinationFile = path.Join(path.Dir(page.DestinationFile), slug+".html")
+		}
+		taken[slug] = page.sourceFile
+	}
+	return nil
+}
+
+// OutputFiles maps every file a build writes to where it comes from, so two
+// sources never silently write the same file.
+type OutputFiles map[string]string
+
+// Add records that source writes dest.
+func (o OutputFiles) Add(dest, source string) error {
+	if other, ok := o[dest]; ok && other != source {
+		return fmt.Errorf("%s would be written by both %s and %s", dest, other, source)
+	}
+	o[dest] = source
+	return nil
+}
@@ -0,0 +1,55 @@
+package solarwind
+
+import "testing"
+
+func TestUniqueSlugs(t *testing.T) {
+	pages := func() []MarkdownPage {
+		return []MarkdownPage{
+			{Slug: "update", RelLink: "posts/update.html", DestinationFile: "/public/posts/update.html", sourceFile: "content/posts/c.md"},
+			{Slug: "update", RelLink: "posts/update.html", DestinationFile: "/public/posts/update.html", sourceFile: "content/posts/a.md"},
+			{Slug: "update-2", RelLink: "posts/update-2.html", DestinationFile: "/public/posts/update-2.html", sourceFile: "content/posts/b.md"},
+			{Slug: "other", RelLink: "posts/other.html", DestinationFile: "/public/posts/other.html", sourceFile: "content/posts/d.md"},
+		}
+	}
+
+	if err := UniqueSlugs(pages(), false); err == nil {
+		t.Error("expected two update slugs to be an error")
+	}
+
+	list := pages()
+	if err := UniqueSlugs(list, true); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"update-3", "update", "update-2", "other"}
+	for i, slug := range expected {
+		if list[i].Slug != slug {
+			t.Errorf("%s: expected slug %s, got %s", list[i].sourceFile, slug, list[i].Slug)
+		}
+	}
+	if list[0].RelLink != "posts/update-3.html" || list[0].DestinationFile != "/public/posts/update-3.html" {
+		t.Errorf("unexpected paths %s %s", list[0].RelLink, list[0].DestinationFile)
+	}
+}
+
+func TestOutputFiles(t *testing.T) {
+	outputs := OutputFiles{}
+	if err := outputs.Add("public/index.html", "content/index.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := outputs.Add("public/index.html", "content/index.md"); err != nil {
+		t.Errorf("the same source writing a file twice isn't a collision: %s", err)
+	}
+	if err := outputs.Add("public/index.html", "content/index.html"); err == nil {
+		t.Error("expected two sources writing public/index.html to be an error")
+	}
+}
+
+func TestUntitledPageSlug(t *testing.T) {
+	page, err := NewMarkdownPage("My_Notes", "Just some notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Slug != "my-notes" || page.RelLink != "posts/my-notes.html" {
+		t.Errorf("expected the filename to be the slug, got %s and %s", page.Slug, page.RelLink)
+	}
+}
@@ -1,73 +1,66 @@
-package main
+// Package solarwind generates static sites from markdown, HTML and data
+// files laid out by a Solarwindfile.
+//
+//	site, err := solarwind.Open("path/to/project", "")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	err = site.Build(context.Background(), site.DestinationDir)
+//
+// The solarwind command, in the command package, is a thin wrapper around
+// this package.
+package solarwind
 
 import (
-	"log"
-	"os"
-	"path"
+	"fmt"
 	"path/filepath"
-
-	"github.com/mitchellh/cli"
-)
-
-var (
-	CurrentPath              string
-	DefaultContentDir        string
-	DefaultPostsDir          string
-	DefaultDestinationDir    string
-	DefaultTemplateDir       string
-	DefaultSolarwindfilePath string
-	DefaultStaticDir         string
 )
 
 const Solarwindfile = "Solarwindfile"
 
-func init() {
-	dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	CurrentPath = dir
-	DefaultContentDir = path.Join(dir, "content")
-	DefaultPostsDir = path.Join(DefaultContentDir, "posts")
-	DefaultDestinationDir = path.Join(CurrentPath, "public")
-	DefaultTemplateDir = path.Join(dir, "templates")
-	DefaultSolarwindfilePath = path.Join(CurrentPath, Solarwindfile)
-	DefaultStaticDir = path.Join(CurrentPath, "static")
-
-	// Sanity check. Make sure a couple of these things exist
-	for _, node := range []string{DefaultSolarwindfilePath, DefaultContentDir, DefaultPostsDir, DefaultTemplateDir} {
-		if _, err := os.Stat(node); err != nil {
-			if os.IsNotExist(err) {
-				log.Fatalf("%s does not exist. It must exist to continue generating a site.", node)
-			} else {
-				panic(err)
-			}
-		}
-	}
+// Layout describes where a project keeps its content, templates, static
+// assets and generated output. Relative directories are resolved against the
+// project root.
+type Layout struct {
+	ContentDir  string `json:"content_dir"`
+	TemplateDir string `json:"template_dir"`
+	StaticDir   string `json:"static_dir"`
+	OutputDir   string `json:"output_dir"`
+	ThemesDir   string `json:"themes_dir"`
+	CacheDir    string `json:"cache_dir"`
+	DataDir     string `json:"data_dir"`
+	I18nDir     string `json:"i18n_dir"`
 }
 
-func main() {
-	c := cli.NewCLI("nbsssg", "0.1.0")
-	ui := &cli.BasicUi{Writer: os.Stdout}
-	c.Args = os.Args[1:]
-	c.Commands = map[string]cli.CommandFactory{
-		"generate": func() (cli.Command, error) {
-			return &GenerateCommand{
-				Ui: ui,
-			}, nil
-		},
-		"server": func() (cli.Command, error) {
-			return &ServerCommand{
-				Ui: ui,
-			}, nil
-		},
-	}
+// DefaultLayout is used for any directory the Solarwindfile doesn't mention.
+var DefaultLayout = Layout{
+	ContentDir:  "content",
+	TemplateDir: "templates",
+	StaticDir:   "static",
+	OutputDir:   "public",
+	ThemesDir:   "themes",
+	CacheDir:    ".cache",
+	DataDir:     "data",
+	I18nDir:     "i18n",
+}
 
-	exitCode, err := c.Run()
+// FindProjectRoot walks upward from start until it finds a directory
+// containing a Solarwindfile.
+func FindProjectRoot(start string) (string, error) {
+	dir, err := filepath.Abs(start)
 	if err != nil {
-		log.Println(err)
+		return "", err
 	}
 
-	os.Exit(exitCode)
+	for {
+		if _, ok := FindSolarwindfile(dir, Solarwindfile); ok {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("could not find a %s in %s or any of its parents", Solarwindfile, start)
+		}
+		dir = parent
+	}
 }
@@ -1,13 +1,171 @@
-package main
+package solarwind
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCanParseHeaderBeforeMarkdown(t *testing.T) {
+	page, err := NewMarkdownPage("hello", "###\ntitle: Hello\ncategory: go\n###\n\nSome *markdown*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "Hello" || page.Category != "go" || strings.TrimSpace(page.RawMarkdown) != "Some *markdown*" {
+		t.Errorf("unexpected page %+v", page)
+	}
+}
 
+func TestMalformedHeaderLinesAreErrors(t *testing.T) {
+	for _, raw := range []string{
+		"###\ntitle: Hello\n\ncategory: go\n###\n\nbody",
+		"###\ntitle: Hello\nno colon here\n###\n\nbody",
+	} {
+		_, err := NewMarkdownPage("hello", raw)
+		if err == nil || !strings.Contains(err.Error(), "Malformed header line") {
+			t.Errorf("expected a malformed header error for %q, got %v", raw, err)
+		}
+	}
 }
 
 func TestCanBuildAFullSite(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := NewSite(root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPost(filepath.Join(root, "content", "posts"), "Hello World", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	site := New(NewConfig())
+	site.Root = root
+	if err := site.Load(); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(root, "out")
+	if err := site.Build(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"index.html", "search.html", "posts/hello-world.html"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Errorf("expected %s to be built: %s", name, err)
+		}
+	}
+	if _, err := os.Stat(site.DestinationDir); !os.IsNotExist(err) {
+		t.Error("expected nothing to be written to the default output directory")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := site.Build(ctx, out); err != context.Canceled {
+		t.Errorf("expected a cancelled build to stop, got %v", err)
+	}
+}
+
+func TestFindProjectRootSearchesUpward(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	nested := filepath.Join(root, "content", "posts")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, Solarwindfile), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := FindProjectRoot(nested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != root {
+		t.Errorf("expected root %s, got %s", root, found)
+	}
+}
+
+func TestOpenResolvesLayout(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, dir := range []string{"src/posts", "layouts"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := `{"content_dir": "src", "template_dir": "layouts", "output_dir": "/tmp/site"}`
+	if err := ioutil.WriteFile(filepath.Join(root, Solarwindfile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	site, err := Open(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct{ got, want string }{
+		{site.ContentDir, filepath.Join(root, "src")},
+		{site.PostsDir, filepath.Join(root, "src", "posts")},
+		{site.TemplateDir, filepath.Join(root, "layouts")},
+		{site.StaticDir, filepath.Join(root, "static")},
+		{site.DestinationDir, "/tmp/site"},
+	} {
+		if c.got != c.want {
+			t.Errorf("expected %s, got %s", c.want, c.got)
+		}
+	}
+}
+
+func TestBuildRefusesToDeleteTheProject(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := NewSite(root); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{".", "..", "content", "content/posts", "templates"} {
+		config := NewConfig()
+		config.Layout.OutputDir = dir
+		site := New(config)
+		site.Root = root
+		if err := site.Load(); err != nil {
+			t.Fatal(err)
+		}
+		if err := site.Build(context.Background(), site.DestinationDir); err == nil {
+			t.Errorf("expected an output_dir of %s to be refused", dir)
+		}
+	}
 
+	site, err := Open(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := site.Build(context.Background(), root); err == nil {
+		t.Error("expected building into the project root to be refused")
+	}
+	if _, err := os.Stat(filepath.Join(root, Solarwindfile)); err != nil {
+		t.Errorf("expected the project to be left alone: %s", err)
+	}
+	if err := site.Build(context.Background(), filepath.Join(root, "public")); err != nil {
+		t.Errorf("expected the default output directory to work, got %s", err)
+	}
 }
@@ -0,0 +1,136 @@
+package solarwind
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	SortAscending  = "asc"
+	SortDescending = "desc"
+)
+
+// SectionOptions controls how the pages of a section are ordered. Sections
+// are the posts and each data collection, configured under `sections` by
+// name.
+type SectionOptions struct {
+	// SortBy is date, title, weight, filename or the name of any other
+	// header field.
+	SortBy string `json:"sort_by"`
+
+	// Order is asc or desc. Dates default to newest first and everything
+	// else to ascending.
+	Order string `json:"order"`
+}
+
+// Section returns the sort options of the named section, with defaults
+// filled in.
+func (c *Config) Section(name string) SectionOptions {
+	options := c.Sections[name]
+	if options.SortBy == "" {
+		options.SortBy = "date"
+	}
+	if options.Order == "" {
+		options.Order = SortAscending
+		if options.SortBy == "date" {
+			options.Order = SortDescending
+		}
+	}
+	return options
+}
+
+// SortPages orders pages by options. Pages missing the sort field (no date,
+// no title, a weight of zero or no such header) always go last. Ties, and
+// pages that are both missing the field, are ordered by filename so builds
+// are repeatable.
+func SortPages(pages []MarkdownPage, options SectionOptions) {
+	sort.SliceStable(pages, func(i, j int) bool {
+		c, ok := comparePages(pages[i], pages[j], options.SortBy)
+		if ok && c != 0 {
+			if options.Order == SortDescending {
+				return c > 0
+			}
+			return c < 0
+		}
+		if !ok && c != 0 {
+			return c < 0
+		}
+		return pages[i].Filename < pages[j].Filename
+	})
+}
+
+// comparePages compares a and b by field. ok is false if either is missing
+// the field, in which case the result only says which one has it. Dates and
+// weights come from Params when neither page has them set, which is where
+// collection pages keep them.
+func comparePages(a, b MarkdownPage, field string) (c int, ok bool) {
+	switch field {
+	case "date":
+		if a.Date.IsZero() && b.Date.IsZero() {
+			break
+		}
+		if missing := compareMissing(a.Date.IsZero(), b.Date.IsZero()); missing != 0 || a.Date.IsZero() {
+			return missing, false
+		}
+		switch {
+		case a.Date.Before(b.Date):
+			return -1, true
+		case a.Date.After(b.Date):
+			return 1, true
+		}
+		return 0, true
+	case "title":
+		if missing := compareMissing(a.Title == "", b.Title == ""); missing != 0 || a.Title == "" {
+			return missing, false
+		}
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)), true
+	case "weight":
+		if a.Weight == 0 && b.Weight == 0 {
+			break
+		}
+		if missing := compareMissing(a.Weight == 0, b.Weight == 0); missing != 0 || a.Weight == 0 {
+			return missing, false
+		}
+		return a.Weight - b.Weight, true
+	case "filename":
+		return strings.Compare(a.Filename, b.Filename), true
+	}
+
+	av, aok := a.Params[field]
+	bv, bok := b.Params[field]
+	if missing := compareMissing(!aok, !bok); missing != 0 || !aok {
+		return missing, false
+	}
+	return compareValues(av, bv), true
+}
+
+// compareMissing puts pages that have a value before those that don't.
+func compareMissing(a, b bool) int {
+	switch {
+	case a && !b:
+		return 1
+	case !a && b:
+		return -1
+	}
+	return 0
+}
+
+// compareValues compares numerically if both values are numbers and as
+// strings otherwise.
+func compareValues(a, b interface{}) int {
+	as, bs := fmt.Sprint(a), fmt.Sprint(b)
+	af, aerr := strconv.ParseFloat(as, 64)
+	bf, berr := strconv.ParseFloat(bs, 64)
+	if aerr == nil && berr == nil {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(as, bs)
+}
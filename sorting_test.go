@@ -0,0 +1,60 @@
+package solarwind
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSortPages(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2015, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+	pages := func() []MarkdownPage {
+		return []MarkdownPage{
+			{Filename: "d", Title: "Zebra", Date: day(2), Weight: 2},
+			{Filename: "c"},
+			{Filename: "a", Title: "apple", Date: day(3), Weight: 1, Params: map[string]interface{}{"price": "10"}},
+			{Filename: "b", Title: "Mango", Date: day(2), Params: map[string]interface{}{"price": "9"}},
+		}
+	}
+
+	tests := []struct {
+		options  SectionOptions
+		expected string
+	}{
+		{SectionOptions{SortBy: "date", Order: SortDescending}, "abdc"},
+		{SectionOptions{SortBy: "date", Order: SortAscending}, "bdac"},
+		{SectionOptions{SortBy: "title", Order: SortAscending}, "abdc"},
+		{SectionOptions{SortBy: "title", Order: SortDescending}, "dbac"},
+		{SectionOptions{SortBy: "weight", Order: SortAscending}, "adbc"},
+		{SectionOptions{SortBy: "filename", Order: SortDescending}, "dcba"},
+		{SectionOptions{SortBy: "price", Order: SortAscending}, "bacd"},
+	}
+	for _, test := range tests {
+		list := pages()
+		SortPages(list, test.options)
+		var got string
+		for _, page := range list {
+			got += page.Filename
+		}
+		if got != test.expected {
+			t.Errorf("%+v: expected %s, got %s", test.options, test.expected, got)
+		}
+	}
+}
+
+func TestConfigSection(t *testing.T) {
+	config := NewConfig()
+	config.Sections["docs"] = SectionOptions{SortBy: "weight"}
+	if options := config.Section("posts"); options.SortBy != "date" || options.Order != SortDescending {
+		t.Errorf("unexpected default section %+v", options)
+	}
+	if options := config.Section("docs"); options.SortBy != "weight" || options.Order != SortAscending {
+		t.Errorf("unexpected docs section %+v", options)
+	}
+
+	config.Sections["docs"] = SectionOptions{SortBy: "weight", Order: "sideways"}
+	if err := config.Validate(); err == nil {
+		t.Error("expected an invalid order to fail validation")
+	}
+}
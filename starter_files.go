@@ -0,0 +1,15 @@
+// Code generated by starter_gen.go; DO NOT EDIT.
+
+package solarwind
+
+// starterFiles holds the contents of starter/, keyed by slash separated path.
+var starterFiles = map[string]string{
+	"Solarwindfile":                    "{\n    \"site_title\": \"My Solarwind Site\",\n    \"site_description\": \"This is a static site generated with Solarwind\",\n    \"menus\": {\n        \"main\": [\n            {\"name\": \"Home\", \"url\": \"/index.html\", \"weight\": 1}\n        ]\n    }\n}\n",
+	"content/index.md":                 "###\ntitle: Welcome\n###\n\nThis is your new Solarwind site. Edit `content/index.md` to change this page,\nor run `solarwind new post \"My First Post\"` to start writing.\n",
+	"content/search.html":              "{{define \"content\"}}\n<h1>Search</h1>\n<input type=\"search\" id=\"search\" placeholder=\"Search\" autofocus>\n<ul id=\"results\"></ul>\n<script>\n(function () {\n  var input = document.getElementById(\"search\");\n  var results = document.getElementById(\"results\");\n  var index = [];\n\n  function search() {\n    var terms = input.value.toLowerCase().split(/\\s+/).filter(Boolean);\n    results.innerHTML = \"\";\n    if (!terms.length) {\n      return;\n    }\n    index.filter(function (doc) {\n      var haystack = [doc.title, (doc.tags || []).join(\" \"), doc.summary, doc.text].join(\" \").toLowerCase();\n      return terms.every(function (term) {\n        return haystack.indexOf(term) >= 0;\n      });\n    }).forEach(function (doc) {\n      var item = document.createElement(\"li\");\n      var link = document.createElement(\"a\");\n      link.href = doc.url;\n      link.textContent = doc.title || doc.url;\n      item.appendChild(link);\n      if (doc.summary) {\n        item.appendChild(document.createTextNode(\" \" + doc.summary));\n      }\n      results.appendChild(item);\n    });\n  }\n\n  var request = new XMLHttpRequest();\n  request.open(\"GET\", \"search-index.json\");\n  request.onload = function () {\n    index = JSON.parse(request.responseText);\n    search();\n  };\n  request.send();\n  input.addEventListener(\"input\", search);\n})();\n</script>\n{{end}}\n{{define \"site-title\"}}{{ .SiteTitle }}Search{{end}}\n",
+	"templates/index.html":             "<!doctype html>\n<html>\n  <title>{{template \"site-title\" .}}</title>\n  <head></head>\n  <body>\n    <h1>My Solarwind Site</h1>\n    <nav>\n      <ul>\n        {{range .Site.Menus.main}}\n        <li{{if or (.IsActive $.CurrentPage) (.HasActiveChild $.CurrentPage)}} class=\"active\"{{end}}>\n          <a href=\"{{.URL}}\">{{.Name}}</a>\n          {{if .HasChildren}}\n          <ul>\n            {{range .Children}}\n            <li{{if .IsActive $.CurrentPage}} class=\"active\"{{end}}><a href=\"{{.URL}}\">{{.Name}}</a></li>\n            {{end}}\n          </ul>\n          {{end}}\n        </li>\n        {{end}}\n      </ul>\n    </nav>\n    {{template \"body\" .}}\n  </body>\n</html>\n",
+	"templates/page.html":              "{{define \"body\"}}\n  <div id=\"wrapper\">\n    {{template \"content\" .}}\n  </div>\n{{end}}\n",
+	"templates/post.html":              "{{define \"body\"}}\n<h1>{{ .CurrentPage.Title }}</h1>\n{{ .CurrentPage.FinalHTML }}\n<nav>\n{{ with .CurrentPage.Next }}<a href=\"/{{ .RelLink }}\">&larr; {{ .Title }}</a>{{ end }}\n{{ with .CurrentPage.Prev }}<a href=\"/{{ .RelLink }}\">{{ .Title }} &rarr;</a>{{ end }}\n</nav>\n{{end}}\n{{define \"site-title\"}}{{ .SiteTitle }}{{ .CurrentPage.Title }}{{end}}\n",
+	"templates/shortcodes/figure.html": "<figure>\n  <img src=\"{{ .Get \"src\" }}\" alt=\"{{ or (.Get \"alt\") (.Get \"caption\") }}\" />\n  {{ with .Get \"caption\" }}<figcaption>{{ . }}</figcaption>{{ end }}\n</figure>\n",
+	"templates/shortcodes/note.html":   "<div class=\"note\">{{ markdownify .Inner }}</div>\n",
+}
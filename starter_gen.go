@@ -0,0 +1,66 @@
+//go:build ignore
+// +build ignore
+
+// This program embeds the files under starter/ into starter_files.go so
+// `solarwind new site` works without a copy of the source tree. Run it with
+// `go generate` after changing anything in starter/.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+func main() {
+	files := map[string]string{}
+	err := filepath.Walk("starter", func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel("starter", p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b := &bytes.Buffer{}
+	fmt.Fprintln(b, "// Code generated by starter_gen.go; DO NOT EDIT.")
+	fmt.Fprintln(b)
+	fmt.Fprintln(b, "package solarwind")
+	fmt.Fprintln(b)
+	fmt.Fprintln(b, "// starterFiles holds the contents of starter/, keyed by slash separated path.")
+	fmt.Fprintln(b, "var starterFiles = map[string]string{")
+	for _, name := range names {
+		fmt.Fprintf(b, "%q: %q,\n", name, files[name])
+	}
+	fmt.Fprintln(b, "}")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("starter_files.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
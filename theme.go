@@ -0,0 +1,126 @@
+package solarwind
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// ThemeConfigFile is the name of the file describing a theme, without an
+// extension. Like the Solarwindfile it can be JSON, YAML or TOML.
+const ThemeConfigFile = "theme"
+
+// Theme is a reusable package of templates, static assets and default params
+// kept in a directory under themes/:
+//
+//	themes/<name>/theme.yaml
+//	themes/<name>/templates/
+//	themes/<name>/static/
+//
+// Files in the project's own templates and static directories override theme
+// files with the same path, and site params override theme params.
+type Theme struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Params      map[string]interface{} `json:"params"`
+	Dir         string                 `json:"-"`
+}
+
+// LoadTheme reads the theme in dir. The theme config file is optional.
+func LoadTheme(dir string) (*Theme, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("theme %s does not exist. It must exist to continue generating a site.", dir)
+	}
+
+	theme := &Theme{Name: path.Base(dir), Dir: dir}
+	if p, ok := FindSolarwindfile(dir, ThemeConfigFile); ok {
+		values, err := readConfigFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeStrict(p, values, theme); err != nil {
+			return nil, err
+		}
+	}
+
+	return theme, nil
+}
+
+func (t *Theme) TemplateDir() string {
+	return path.Join(t.Dir, "templates")
+}
+
+func (t *Theme) StaticDir() string {
+	return path.Join(t.Dir, "static")
+}
+
+// MergeParams returns the theme params with site params merged on top.
+func (t *Theme) MergeParams(site map[string]interface{}) map[string]interface{} {
+	params := map[string]interface{}{}
+	mergeConfigValues(params, copyConfigValues(t.Params))
+	mergeConfigValues(params, site)
+	return params
+}
+
+func copyConfigValues(values map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if m, ok := value.(map[string]interface{}); ok {
+			value = copyConfigValues(m)
+		}
+		c[key] = value
+	}
+	return c
+}
+
+// TemplateDirs lists the directories templates are looked up in, most
+// specific first.
+func (s *Site) TemplateDirs() []string {
+	dirs := []string{s.TemplateDir}
+	if s.Theme != nil {
+		dirs = append(dirs, s.Theme.TemplateDir())
+	}
+	return dirs
+}
+
+// StaticDirs lists the directories static assets are copied from. Later
+// directories override earlier ones.
+func (s *Site) StaticDirs() []string {
+	var dirs []string
+	if s.Theme != nil {
+		dirs = append(dirs, s.Theme.StaticDir())
+	}
+	return append(dirs, s.StaticDir)
+}
+
+// StaticSearchDirs lists the static directories in lookup order, most
+// specific first.
+func (s *Site) StaticSearchDirs() []string {
+	dirs := s.StaticDirs()
+	for i, j := 0, len(dirs)-1; i < j; i, j = i+1, j-1 {
+		dirs[i], dirs[j] = dirs[j], dirs[i]
+	}
+	return dirs
+}
+
+// ReadTemplate reads the named template from the project, falling back to
+// the theme.
+func (s *Site) ReadTemplate(name string) ([]byte, error) {
+	return readTemplate(s.TemplateDirs(), name)
+}
+
+// readTemplate reads the named template from the first of dirs that has it.
+func readTemplate(dirs []string, name string) ([]byte, error) {
+	for _, dir := range dirs {
+		content, err := ioutil.ReadFile(path.Join(dir, name))
+		if err == nil {
+			return content, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("template %s was not found in %s", name, strings.Join(dirs, " or "))
+}
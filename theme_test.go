@@ -0,0 +1,58 @@
+package solarwind
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThemeFilesAreOverriddenByTheProject(t *testing.T) {
+	root, err := ioutil.TempDir("", "solarwind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		Solarwindfile:                          `{"theme": "corporate", "params": {"color": "red"}}`,
+		"templates/post.html":                  "project post",
+		"themes/corporate/theme.yaml":          "params:\n  color: blue\n  logo: logo.png\n",
+		"themes/corporate/templates/post.html": "theme post",
+		"themes/corporate/templates/page.html": "theme page",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "content", "posts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	site, err := Open(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{"post.html": "project post", "page.html": "theme page"} {
+		content, err := site.ReadTemplate(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Errorf("expected %s to be %q, got %q", name, expected, content)
+		}
+	}
+	if _, err := site.ReadTemplate("index.html"); err == nil {
+		t.Error("expected a missing template to be an error")
+	}
+
+	if site.Config.Params["color"] != "red" || site.Config.Params["logo"] != "logo.png" {
+		t.Errorf("expected site params to override theme params, got %v", site.Config.Params)
+	}
+}